@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/Davmie/person_service/pkg/config"
+)
+
+const migrateUsage = "usage: main migrate up|down [steps]|status [config flags]"
+
+// runMigrate handles the migrate subcommand. Config flags follow the action,
+// e.g. "migrate down 2 -config config.yaml".
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		log.Fatal(migrateUsage)
+	}
+	action, args := args[0], args[1:]
+
+	steps := 1
+	if action == "down" && len(args) > 0 {
+		if n, err := strconv.Atoi(args[0]); err == nil {
+			steps, args = n, args[1:]
+		}
+	}
+
+	cfg, err := config.Load(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cfg.Storage != config.StoragePostgres {
+		log.Fatal("migrations require postgres storage")
+	}
+
+	zapLogger := newLogger(cfg)
+	defer zapLogger.Sync()
+
+	m := newMigrator(openDB(cfg), zapLogger.Sugar())
+	ctx := context.Background()
+
+	switch action {
+	case "up":
+		applied, err := m.Up(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("applied %d migration(s)\n", len(applied))
+	case "down":
+		reverted, err := m.Down(ctx, steps)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("reverted %d migration(s)\n", len(reverted))
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, st := range statuses {
+			fmt.Println(st)
+		}
+	default:
+		log.Fatal(migrateUsage)
+	}
+}
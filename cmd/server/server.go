@@ -1,28 +1,108 @@
 package server
 
 import (
+	"context"
 	"log"
+	"net"
 	"net/http"
 	"time"
+
+	"github.com/Davmie/person_service/pkg/config"
+	"github.com/pkg/errors"
 )
 
+type closer struct {
+	name  string
+	close func() error
+}
+
 type Server struct {
 	http.Server
+	ShutdownTimeout time.Duration
+	// BeforeDrain runs as soon as shutdown starts, DrainDelay before new
+	// connections are refused. It should make the server report not ready.
+	BeforeDrain func()
+	DrainDelay  time.Duration
+
+	closers []closer
 }
 
-func NewServer(myHandler http.Handler) *Server {
+func NewServer(myHandler http.Handler, cfg config.ServerConfig) *Server {
 	return &Server{
-		http.Server{
-			Addr:              ":8080",
+		Server: http.Server{
+			Addr:              cfg.Addr,
 			Handler:           myHandler,
-			ReadTimeout:       10 * time.Second,
-			ReadHeaderTimeout: 10 * time.Second,
-			WriteTimeout:      10 * time.Second,
+			ReadTimeout:       cfg.ReadTimeout,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
 		},
+		ShutdownTimeout: cfg.ShutdownTimeout,
+		DrainDelay:      cfg.DrainDelay,
+	}
+}
+
+// OnShutdown registers fn to run after the server has drained. Closers run
+// in the order they were registered.
+func (s *Server) OnShutdown(name string, fn func() error) {
+	s.closers = append(s.closers, closer{name: name, close: fn})
+}
+
+// Run serves until ctx is done, then runs BeforeDrain, keeps serving for
+// DrainDelay, stops accepting connections, waits up to ShutdownTimeout for
+// in-flight requests and runs the closers.
+func (s *Server) Run(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		s.close()
+		return errors.Wrap(err, "can`t listen")
 	}
+
+	return s.run(ctx, ln)
 }
 
-func (s *Server) Start() error {
-	log.Println("Start server on :8080")
-	return s.ListenAndServe()
+func (s *Server) run(ctx context.Context, ln net.Listener) error {
+	log.Println("Start server on " + ln.Addr().String())
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.Serve(ln)
+	}()
+
+	var err error
+	select {
+	case err = <-serveErr:
+		err = errors.Wrap(err, "server stopped")
+	case <-ctx.Done():
+		log.Println("Shutting down server")
+
+		if s.BeforeDrain != nil {
+			s.BeforeDrain()
+		}
+		time.Sleep(s.DrainDelay)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), s.ShutdownTimeout)
+		defer cancel()
+
+		if err = s.Shutdown(shutdownCtx); err != nil {
+			err = errors.Wrap(err, "can`t drain in-flight requests")
+			s.Close()
+		}
+	}
+
+	if closeErr := s.close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+func (s *Server) close() error {
+	var err error
+	for _, c := range s.closers {
+		if closeErr := c.close(); closeErr != nil && err == nil {
+			err = errors.Wrapf(closeErr, "can`t close %s", c.name)
+		}
+	}
+
+	return err
 }
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Davmie/person_service/pkg/config"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+)
+
+type ServerTestSuite struct {
+	suite.Suite
+}
+
+func TestServerSuite(t *testing.T) {
+	suite.RunSuite(t, new(ServerTestSuite))
+}
+
+func (s *ServerTestSuite) TestGracefulShutdown(t provider.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "done")
+	})
+
+	srv := NewServer(handler, config.ServerConfig{
+		ReadTimeout:       time.Second,
+		ReadHeaderTimeout: time.Second,
+		WriteTimeout:      5 * time.Second,
+		ShutdownTimeout:   5 * time.Second,
+	})
+
+	var closed []string
+	srv.OnShutdown("database", func() error {
+		closed = append(closed, "database")
+		return nil
+	})
+	srv.OnShutdown("logger", func() error {
+		closed = append(closed, "logger")
+		return nil
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	t.Require().NoError(err)
+	addr := ln.Addr().String()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- srv.run(ctx, ln)
+	}()
+
+	type result struct {
+		body string
+		err  error
+	}
+	inFlight := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr)
+		if err != nil {
+			inFlight <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		inFlight <- result{body: string(body), err: err}
+	}()
+
+	<-started
+	cancel()
+
+	refused := false
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); {
+		conn, err := net.DialTimeout("tcp", addr, 100*time.Millisecond)
+		if err != nil {
+			refused = true
+			break
+		}
+		conn.Close()
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Assert().True(refused, "new connections are refused while draining")
+
+	select {
+	case <-runErr:
+		t.Fatal("server stopped before the in-flight request finished")
+	default:
+	}
+
+	close(release)
+
+	res := <-inFlight
+	t.Assert().NoError(res.err)
+	t.Assert().Equal("done", res.body)
+
+	t.Assert().NoError(<-runErr)
+	t.Assert().Equal([]string{"database", "logger"}, closed)
+}
+
+func (s *ServerTestSuite) TestDrainDelay(t provider.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	})
+
+	srv := NewServer(handler, config.ServerConfig{
+		ReadTimeout:       time.Second,
+		ReadHeaderTimeout: time.Second,
+		WriteTimeout:      time.Second,
+		ShutdownTimeout:   time.Second,
+		DrainDelay:        300 * time.Millisecond,
+	})
+
+	var events []string
+	notReady := make(chan struct{})
+	srv.BeforeDrain = func() {
+		events = append(events, "not ready")
+		close(notReady)
+	}
+	srv.OnShutdown("database", func() error {
+		events = append(events, "database")
+		return nil
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	t.Require().NoError(err)
+	addr := ln.Addr().String()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- srv.run(ctx, ln)
+	}()
+
+	cancel()
+	<-notReady
+	start := time.Now()
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	resp, err := client.Get("http://" + addr)
+	t.Require().NoError(err, "connections are accepted after reporting not ready")
+	resp.Body.Close()
+
+	refused := false
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); {
+		conn, err := net.DialTimeout("tcp", addr, 100*time.Millisecond)
+		if err != nil {
+			refused = true
+			break
+		}
+		conn.Close()
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Assert().True(refused, "new connections are refused after the drain delay")
+	t.Assert().GreaterOrEqual(time.Since(start), 250*time.Millisecond)
+
+	t.Assert().NoError(<-runErr)
+	t.Assert().Equal([]string{"not ready", "database"}, events)
+}
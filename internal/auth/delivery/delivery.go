@@ -0,0 +1,153 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	authUseCase "github.com/Davmie/person_service/internal/auth/usecase"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Davmie/person_service/pkg/logger"
+	"github.com/Davmie/person_service/pkg/response"
+	"github.com/Davmie/person_service/pkg/session"
+	"github.com/Davmie/person_service/pkg/validator"
+)
+
+type TokenRequest struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type AuthHandler struct {
+	AuthUseCase authUseCase.AuthUseCaseI
+	Keys        *session.KeyRing
+	Logger      logger.Logger
+}
+
+func (ah *AuthHandler) Token(w http.ResponseWriter, r *http.Request) {
+	req := TokenRequest{}
+	if !ah.readJSON(w, r, &req) {
+		return
+	}
+
+	v := validator.New()
+	v.Required("client_id", req.ClientID)
+	v.Required("client_secret", req.ClientSecret)
+	if errs := v.Errors(); errs != nil {
+		response.Validation(w, ah.logger(r.Context()), errs)
+		return
+	}
+
+	pair, err := ah.AuthUseCase.Issue(r.Context(), req.ClientID, req.ClientSecret)
+	if err != nil {
+		ah.logger(r.Context()).Infow("can`t issue token",
+			"client_id", req.ClientID,
+			"err:", err.Error())
+		response.Error(w, ah.logger(r.Context()), err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	response.JSON(w, ah.logger(r.Context()), http.StatusOK, pair)
+}
+
+func (ah *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	req := RefreshRequest{}
+	if !ah.readJSON(w, r, &req) {
+		return
+	}
+
+	v := validator.New()
+	v.Required("refresh_token", req.RefreshToken)
+	if errs := v.Errors(); errs != nil {
+		response.Validation(w, ah.logger(r.Context()), errs)
+		return
+	}
+
+	pair, err := ah.AuthUseCase.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		ah.logger(r.Context()).Infow("can`t refresh token",
+			"err:", err.Error())
+		response.Error(w, ah.logger(r.Context()), err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	response.JSON(w, ah.logger(r.Context()), http.StatusOK, pair)
+}
+
+// Logout revokes the bearer access token and the refresh token from the
+// request body.
+func (ah *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	accessToken := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if accessToken == "" {
+		response.Message(w, ah.logger(r.Context()), http.StatusUnauthorized, "no auth")
+		return
+	}
+
+	req := RefreshRequest{}
+	if !ah.readJSON(w, r, &req) {
+		return
+	}
+
+	v := validator.New()
+	v.Required("refresh_token", req.RefreshToken)
+	if errs := v.Errors(); errs != nil {
+		response.Validation(w, ah.logger(r.Context()), errs)
+		return
+	}
+
+	err := ah.AuthUseCase.Logout(r.Context(), accessToken, req.RefreshToken)
+	if err != nil {
+		ah.logger(r.Context()).Infow("can`t logout",
+			"err:", err.Error())
+		response.Error(w, ah.logger(r.Context()), err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// JWKS publishes the public signing keys so other services can verify our
+// tokens offline.
+func (ah *AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	response.JSON(w, ah.logger(r.Context()), http.StatusOK, ah.Keys.JWKS())
+}
+
+func (ah *AuthHandler) readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		ah.logger(r.Context()).Errorw("can`t read body of request",
+			"err:", err.Error())
+		response.Message(w, ah.logger(r.Context()), http.StatusBadRequest, "bad data")
+		return false
+	}
+
+	err = r.Body.Close()
+	if err != nil {
+		ah.logger(r.Context()).Errorw("can`t close body of request", "err:", err.Error())
+		response.Message(w, ah.logger(r.Context()), http.StatusInternalServerError, "close error")
+		return false
+	}
+
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		ah.logger(r.Context()).Infow("can`t unmarshal form",
+			"err:", err.Error())
+		response.Message(w, ah.logger(r.Context()), http.StatusBadRequest, "bad data")
+		return false
+	}
+
+	return true
+}
+
+// logger returns the request-scoped logger, which carries the request id.
+func (ah *AuthHandler) logger(ctx context.Context) logger.Logger {
+	return logger.FromContext(ctx, ah.Logger)
+}
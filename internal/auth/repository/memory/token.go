@@ -0,0 +1,47 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Davmie/person_service/internal/auth/repository"
+)
+
+type memTokenRepo struct {
+	mu      sync.RWMutex
+	revoked map[string]time.Time
+}
+
+func New() repository.TokenRepositoryI {
+	return &memTokenRepo{
+		revoked: make(map[string]time.Time),
+	}
+}
+
+func (tr *memTokenRepo) Revoke(_ context.Context, jti string, expiresAt time.Time) (bool, error) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	now := time.Now()
+	for id, exp := range tr.revoked {
+		if exp.Before(now) {
+			delete(tr.revoked, id)
+		}
+	}
+
+	if _, ok := tr.revoked[jti]; ok {
+		return false, nil
+	}
+	tr.revoked[jti] = expiresAt
+
+	return true, nil
+}
+
+func (tr *memTokenRepo) IsRevoked(_ context.Context, jti string) (bool, error) {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+
+	_, ok := tr.revoked[jti]
+	return ok, nil
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Davmie/person_service/pkg/metrics"
+)
+
+type metricsTokenRepo struct {
+	next    TokenRepositoryI
+	metrics *metrics.Metrics
+}
+
+// WithMetrics records the latency and result of every call to repo.
+func WithMetrics(repo TokenRepositoryI, m *metrics.Metrics) TokenRepositoryI {
+	return &metricsTokenRepo{next: repo, metrics: m}
+}
+
+func (mr *metricsTokenRepo) Revoke(ctx context.Context, jti string, expiresAt time.Time) (bool, error) {
+	start := time.Now()
+	revoked, err := mr.next.Revoke(ctx, jti, expiresAt)
+	mr.metrics.ObserveQuery("token", "Revoke", start, err)
+
+	return revoked, err
+}
+
+func (mr *metricsTokenRepo) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	start := time.Now()
+	revoked, err := mr.next.IsRevoked(ctx, jti)
+	mr.metrics.ObserveQuery("token", "IsRevoked", start, err)
+
+	return revoked, err
+}
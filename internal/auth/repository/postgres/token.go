@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/Davmie/person_service/internal/auth/repository"
+	"github.com/Davmie/person_service/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type pgTokenRepo struct {
+	DB *gorm.DB
+}
+
+func New(db *gorm.DB) repository.TokenRepositoryI {
+	return &pgTokenRepo{
+		DB: db,
+	}
+}
+
+func (tr *pgTokenRepo) Revoke(ctx context.Context, jti string, expiresAt time.Time) (bool, error) {
+	tx := tr.DB.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&models.RevokedToken{})
+
+	if tx.Error != nil {
+		return false, errors.Wrap(tx.Error, "pgTokenRepo.Revoke error while purging expired tokens")
+	}
+
+	tx = tr.DB.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.RevokedToken{JTI: jti, ExpiresAt: expiresAt})
+
+	if tx.Error != nil {
+		return false, errors.Wrap(tx.Error, "pgTokenRepo.Revoke error")
+	}
+
+	return tx.RowsAffected == 1, nil
+}
+
+func (tr *pgTokenRepo) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	var count int64
+	tx := tr.DB.WithContext(ctx).Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count)
+
+	if tx.Error != nil {
+		return false, errors.Wrap(tx.Error, "pgTokenRepo.IsRevoked error")
+	}
+
+	return count > 0, nil
+}
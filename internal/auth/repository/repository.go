@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+type TokenRepositoryI interface {
+	// Revoke adds jti to the revocation list and reports whether it was not
+	// revoked before.
+	Revoke(ctx context.Context, jti string, expiresAt time.Time) (bool, error)
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
@@ -0,0 +1,133 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	authRep "github.com/Davmie/person_service/internal/auth/repository"
+	"github.com/Davmie/person_service/models"
+	"github.com/Davmie/person_service/pkg/session"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type AuthUseCaseI interface {
+	Issue(ctx context.Context, clientID, secret string) (*models.TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (*models.TokenPair, error)
+	Logout(ctx context.Context, accessToken, refreshToken string) error
+}
+
+type authUseCase struct {
+	sessions        session.JWTSessionsManager
+	tokenRepository authRep.TokenRepositoryI
+	clients         map[string]models.Client
+	// dummyHash is checked for unknown clients, so that they cost as much
+	// time as a wrong secret and do not reveal which client IDs exist.
+	dummyHash []byte
+}
+
+func New(sessions session.JWTSessionsManager, tRep authRep.TokenRepositoryI, clients []models.Client) AuthUseCaseI {
+	byID := make(map[string]models.Client, len(clients))
+	cost := 0
+	for _, c := range clients {
+		byID[c.ID] = c
+		if hashCost, err := bcrypt.Cost([]byte(c.SecretHash)); err == nil {
+			cost = max(cost, hashCost)
+		}
+	}
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+
+	dummyHash, _ := bcrypt.GenerateFromPassword([]byte("unknown client"), cost)
+
+	return &authUseCase{
+		sessions:        sessions,
+		tokenRepository: tRep,
+		clients:         byID,
+		dummyHash:       dummyHash,
+	}
+}
+
+func (aUC *authUseCase) Issue(ctx context.Context, clientID, secret string) (*models.TokenPair, error) {
+	client, ok := aUC.clients[clientID]
+	if !ok {
+		_ = bcrypt.CompareHashAndPassword(aUC.dummyHash, []byte(secret))
+		return nil, errors.Wrap(models.ErrUnauthorized, "authUseCase.Issue error: unknown client")
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(client.SecretHash), []byte(secret))
+	if err != nil {
+		return nil, errors.Wrap(models.ErrUnauthorized, "authUseCase.Issue error: wrong secret")
+	}
+
+	return aUC.pair(client.UserID, client.Role)
+}
+
+// Refresh exchanges a refresh token for a new pair. The old refresh token is
+// revoked first, so each one can be used only once.
+func (aUC *authUseCase) Refresh(ctx context.Context, refreshToken string) (*models.TokenPair, error) {
+	claims, err := aUC.sessions.ParseToken(ctx, refreshToken, session.RefreshToken)
+	if err != nil {
+		return nil, errors.Wrap(models.ErrUnauthorized, "authUseCase.Refresh error: "+err.Error())
+	}
+
+	fresh, err := aUC.tokenRepository.Revoke(ctx, claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		return nil, errors.Wrap(err, "authUseCase.Refresh error: Can't revoke in repo")
+	}
+	if !fresh {
+		return nil, errors.Wrap(models.ErrUnauthorized, "authUseCase.Refresh error: token already used")
+	}
+
+	return aUC.pair(claims.User.ID, claims.User.Role)
+}
+
+// Logout revokes the access token and the refresh token issued with it. The
+// refresh token is required, it could otherwise keep minting access tokens.
+func (aUC *authUseCase) Logout(ctx context.Context, accessToken, refreshToken string) error {
+	if refreshToken == "" {
+		return errors.Wrap(models.ErrValidation, "authUseCase.Logout error: refresh token is required")
+	}
+
+	claims, err := aUC.sessions.ParseToken(ctx, accessToken, session.AccessToken)
+	if err != nil {
+		return errors.Wrap(models.ErrUnauthorized, "authUseCase.Logout error: "+err.Error())
+	}
+
+	refreshClaims, err := aUC.sessions.ParseToken(ctx, refreshToken, session.RefreshToken)
+	if err != nil {
+		return errors.Wrap(models.ErrUnauthorized, "authUseCase.Logout error: "+err.Error())
+	}
+	if refreshClaims.User != claims.User {
+		return errors.Wrap(models.ErrUnauthorized, "authUseCase.Logout error: tokens belong to different users")
+	}
+
+	for _, c := range []*session.Claims{claims, refreshClaims} {
+		_, err = aUC.tokenRepository.Revoke(ctx, c.ID, c.ExpiresAt.Time)
+		if err != nil {
+			return errors.Wrap(err, "authUseCase.Logout error: Can't revoke in repo")
+		}
+	}
+
+	return nil
+}
+
+func (aUC *authUseCase) pair(userID int, role string) (*models.TokenPair, error) {
+	access, err := aUC.sessions.CreateSession(userID, role)
+	if err != nil {
+		return nil, errors.Wrap(err, "authUseCase error: can't create access token")
+	}
+
+	refresh, err := aUC.sessions.CreateRefreshToken(userID, role)
+	if err != nil {
+		return nil, errors.Wrap(err, "authUseCase error: can't create refresh token")
+	}
+
+	return &models.TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(aUC.sessions.AccessTTL / time.Second),
+	}, nil
+}
@@ -0,0 +1,120 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	memToken "github.com/Davmie/person_service/internal/auth/repository/memory"
+	"github.com/Davmie/person_service/models"
+	"github.com/Davmie/person_service/pkg/session"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type AuthTestSuite struct {
+	suite.Suite
+	uc       AuthUseCaseI
+	sessions session.JWTSessionsManager
+}
+
+func TestAuthTestSuite(t *testing.T) {
+	suite.RunSuite(t, new(AuthTestSuite))
+}
+
+func (s *AuthTestSuite) BeforeEach(t provider.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	t.Require().NoError(err)
+
+	tokenRepo := memToken.New()
+	s.sessions = session.NewJWTSessionsManager("test-secret")
+	s.sessions.Revocations = tokenRepo
+
+	s.uc = New(s.sessions, tokenRepo, []models.Client{
+		{ID: "hr", SecretHash: string(hash), UserID: 7, Role: "editor"},
+	})
+}
+
+func (s *AuthTestSuite) TestIssue(t provider.T) {
+	cases := map[string]struct {
+		ClientID string
+		Secret   string
+		Error    error
+	}{
+		"success": {
+			ClientID: "hr",
+			Secret:   "secret",
+			Error:    nil,
+		},
+		"wrong secret": {
+			ClientID: "hr",
+			Secret:   "guess",
+			Error:    models.ErrUnauthorized,
+		},
+		"unknown client": {
+			ClientID: "nobody",
+			Secret:   "secret",
+			Error:    models.ErrUnauthorized,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t provider.T) {
+			_, err := s.uc.Issue(context.Background(), test.ClientID, test.Secret)
+			t.Assert().ErrorIs(err, test.Error)
+		})
+	}
+}
+
+func (s *AuthTestSuite) TestIssueUnknownClientHashes(t provider.T) {
+	cost, err := bcrypt.Cost(s.uc.(*authUseCase).dummyHash)
+	t.Require().NoError(err)
+	t.Assert().Equal(bcrypt.MinCost, cost)
+}
+
+func (s *AuthTestSuite) TestRefreshRotates(t provider.T) {
+	ctx := context.Background()
+	pair, err := s.uc.Issue(ctx, "hr", "secret")
+	t.Require().NoError(err)
+
+	_, err = s.uc.Refresh(ctx, pair.AccessToken)
+	t.Assert().ErrorIs(err, models.ErrUnauthorized)
+
+	next, err := s.uc.Refresh(ctx, pair.RefreshToken)
+	t.Require().NoError(err)
+
+	userID, role, err := s.sessions.GetUser(ctx, next.AccessToken)
+	t.Assert().NoError(err)
+	t.Assert().Equal(7, userID)
+	t.Assert().Equal("editor", role)
+
+	_, err = s.uc.Refresh(ctx, pair.RefreshToken)
+	t.Assert().ErrorIs(err, models.ErrUnauthorized)
+}
+
+func (s *AuthTestSuite) TestLogoutRequiresRefreshToken(t provider.T) {
+	ctx := context.Background()
+	pair, err := s.uc.Issue(ctx, "hr", "secret")
+	t.Require().NoError(err)
+
+	err = s.uc.Logout(ctx, pair.AccessToken, "")
+	t.Assert().ErrorIs(err, models.ErrValidation)
+
+	_, _, err = s.sessions.GetUser(ctx, pair.AccessToken)
+	t.Assert().NoError(err, "nothing is revoked")
+}
+
+func (s *AuthTestSuite) TestLogout(t provider.T) {
+	ctx := context.Background()
+	pair, err := s.uc.Issue(ctx, "hr", "secret")
+	t.Require().NoError(err)
+
+	err = s.uc.Logout(ctx, pair.AccessToken, pair.RefreshToken)
+	t.Require().NoError(err)
+
+	_, _, err = s.sessions.GetUser(ctx, pair.AccessToken)
+	t.Assert().Error(err)
+
+	_, err = s.uc.Refresh(ctx, pair.RefreshToken)
+	t.Assert().ErrorIs(err, models.ErrUnauthorized)
+}
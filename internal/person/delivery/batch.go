@@ -0,0 +1,171 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/Davmie/person_service/models"
+	"github.com/Davmie/person_service/pkg/response"
+	"github.com/Davmie/person_service/pkg/validator"
+	"github.com/pkg/errors"
+)
+
+const (
+	batchAtomic     = "atomic"
+	batchBestEffort = "best_effort"
+)
+
+type batchRequest struct {
+	// Mode is atomic, the default, or best_effort.
+	Mode       string           `json:"mode"`
+	Operations []batchOperation `json:"operations"`
+}
+
+type batchOperation struct {
+	Op      string `json:"op"`
+	ID      int    `json:"id"`
+	Version int    `json:"version"`
+	// Data is the person to create or the JSON Merge Patch of an update.
+	Data json.RawMessage `json:"data"`
+}
+
+type batchResult struct {
+	Status  int               `json:"status"`
+	ID      int               `json:"id,omitempty"`
+	Version int               `json:"version,omitempty"`
+	Error   string            `json:"error,omitempty"`
+	Errors  map[string]string `json:"errors,omitempty"`
+}
+
+type batchResponse struct {
+	Results []batchResult `json:"results"`
+}
+
+// Batch applies a list of create, update and delete operations. It responds
+// with 200 when all of them succeeded and 207 otherwise, with the status of
+// every operation in the body.
+func (ah *PersonHandler) Batch(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		ah.logger(r.Context()).Errorw("can`t read body of request",
+			"err:", err.Error())
+		response.Message(w, ah.logger(r.Context()), http.StatusBadRequest, "bad data")
+		return
+	}
+
+	err = r.Body.Close()
+	if err != nil {
+		ah.logger(r.Context()).Errorw("can`t close body of request", "err:", err.Error())
+		response.Message(w, ah.logger(r.Context()), http.StatusInternalServerError, "close error")
+		return
+	}
+
+	var req batchRequest
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		ah.logger(r.Context()).Infow("can`t unmarshal batch",
+			"err:", err.Error())
+		response.Message(w, ah.logger(r.Context()), http.StatusBadRequest, "bad data")
+		return
+	}
+
+	batch, errs := req.batch()
+	if errs != nil {
+		ah.logger(r.Context()).Infow("can`t decode batch",
+			"err:", errs.Error())
+		response.Validation(w, ah.logger(r.Context()), errs)
+		return
+	}
+
+	outcomes, err := ah.PersonUseCase.Batch(r.Context(), batch)
+	if err != nil {
+		ah.logger(r.Context()).Infow("can`t apply batch",
+			"err:", err.Error())
+		response.Error(w, ah.logger(r.Context()), err)
+		return
+	}
+
+	status := http.StatusOK
+	resp := batchResponse{Results: make([]batchResult, len(outcomes))}
+	for i, o := range outcomes {
+		resp.Results[i] = newBatchResult(batch.Operations[i], o)
+		if o.Err != nil {
+			status = http.StatusMultiStatus
+		}
+	}
+
+	response.JSON(w, ah.logger(r.Context()), status, resp)
+}
+
+// batch decodes the data of every operation. Malformed operations fail the
+// whole request, invalid ones only themselves.
+func (req *batchRequest) batch() (*models.Batch, validator.Errors) {
+	v := validator.New()
+	batch := &models.Batch{Operations: make([]models.BatchOperation, len(req.Operations))}
+
+	switch req.Mode {
+	case "", batchAtomic:
+		batch.Atomic = true
+	case batchBestEffort:
+	default:
+		v.Add("mode", fmt.Sprintf("must be %s or %s", batchAtomic, batchBestEffort))
+	}
+
+	for i, item := range req.Operations {
+		op := models.BatchOperation{Op: item.Op, ID: item.ID, Version: item.Version}
+
+		var err error
+		switch item.Op {
+		case models.BatchCreate:
+			op.Person = &models.Person{}
+			err = decodeData(item.Data, op.Person)
+		case models.BatchUpdate:
+			op.Patch = &models.PersonPatch{}
+			err = decodeData(item.Data, op.Patch)
+			op.Patch.ID, op.Patch.Version = item.ID, item.Version
+		}
+		switch {
+		case errors.Is(err, errNoData):
+			v.Add(fmt.Sprintf("operations[%d].data", i), "is required")
+		case err != nil:
+			v.Add(fmt.Sprintf("operations[%d].data", i), "is malformed")
+		}
+
+		batch.Operations[i] = op
+	}
+
+	return batch, v.Errors()
+}
+
+var errNoData = errors.New("no data")
+
+func decodeData(data json.RawMessage, v interface{}) error {
+	if len(data) == 0 {
+		return errNoData
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+func newBatchResult(op models.BatchOperation, o models.BatchOutcome) batchResult {
+	if o.Err != nil {
+		var errs validator.Errors
+		if errors.As(o.Err, &errs) {
+			return batchResult{Status: http.StatusBadRequest, ID: op.ID, Error: models.ErrValidation.Error(), Errors: errs}
+		}
+
+		status, msg := response.Status(o.Err)
+		return batchResult{Status: status, ID: op.ID, Error: msg}
+	}
+
+	switch op.Op {
+	case models.BatchCreate:
+		return batchResult{Status: http.StatusCreated, ID: o.Person.ID, Version: o.Person.Version}
+	case models.BatchDelete:
+		return batchResult{Status: http.StatusNoContent, ID: op.ID}
+	}
+
+	return batchResult{Status: http.StatusOK, ID: o.Person.ID, Version: o.Person.Version}
+}
@@ -0,0 +1,116 @@
+package delivery
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Davmie/person_service/pkg/response"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+)
+
+func (s *PersonHandlerTestSuite) TestBatchDecoding(t provider.T) {
+	tooMany := `{"op":"delete","id":1}` + strings.Repeat(`,{"op":"delete","id":1}`, 1000)
+
+	cases := map[string]struct {
+		Body   string
+		Status int
+		Errors map[string]string
+	}{
+		"malformed body": {
+			Body:   `{"operations":`,
+			Status: http.StatusBadRequest,
+		},
+		"unknown mode": {
+			Body:   `{"mode":"sometimes","operations":[{"op":"delete","id":1}]}`,
+			Status: http.StatusBadRequest,
+			Errors: map[string]string{"mode": "must be atomic or best_effort"},
+		},
+		"missing data": {
+			Body:   `{"operations":[{"op":"create"}]}`,
+			Status: http.StatusBadRequest,
+			Errors: map[string]string{"operations[0].data": "is required"},
+		},
+		"malformed data": {
+			Body:   `{"operations":[{"op":"delete","id":1},{"op":"update","id":1,"data":"name"}]}`,
+			Status: http.StatusBadRequest,
+			Errors: map[string]string{"operations[1].data": "is malformed"},
+		},
+		"no operations": {
+			Body:   `{"operations":[]}`,
+			Status: http.StatusBadRequest,
+			Errors: map[string]string{"operations": "must not be empty"},
+		},
+		"too many operations": {
+			Body:   `{"operations":[` + tooMany + `]}`,
+			Status: http.StatusBadRequest,
+			Errors: map[string]string{"operations": "must have at most 1000 items"},
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t provider.T) {
+			w := s.do(http.MethodPost, "/api/v1/persons:batch", test.Body)
+			t.Assert().Equal(test.Status, w.Code)
+			if test.Errors != nil {
+				var resp response.ValidationErrorResponse
+				decode(t, w, &resp)
+				t.Assert().Equal(test.Errors, resp.Errors)
+			}
+		})
+	}
+}
+
+func (s *PersonHandlerTestSuite) TestBatchBestEffort(t provider.T) {
+	updated := s.create(t, `{"name":"Updated","age":20}`)
+	deleted := s.create(t, `{"name":"Deleted","age":20}`)
+
+	w := s.do(http.MethodPost, "/api/v1/persons:batch", `{"mode":"best_effort","operations":[
+		{"op":"create","data":{"name":"Created","age":30}},
+		{"op":"create","data":{"age":30}},
+		{"op":"update","id":1,"version":1,"data":{"age":21}},
+		{"op":"update","id":100,"data":{"age":21}},
+		{"op":"delete","id":2}
+	]}`)
+	t.Require().Equal(http.StatusMultiStatus, w.Code, w.Body.String())
+
+	var resp batchResponse
+	decode(t, w, &resp)
+	t.Assert().Equal([]batchResult{
+		{Status: http.StatusCreated, ID: 3, Version: 1},
+		{Status: http.StatusBadRequest, Error: "invalid data", Errors: map[string]string{"name": "is required"}},
+		{Status: http.StatusOK, ID: 1, Version: 2},
+		{Status: http.StatusNotFound, ID: 100, Error: "not found"},
+		{Status: http.StatusNoContent, ID: 2},
+	}, resp.Results)
+
+	t.Assert().Equal(http.StatusOK, s.do(http.MethodGet, "/api/v1/persons/3", "").Code)
+	t.Assert().Equal(etag(2), s.do(http.MethodGet, updated, "").Header().Get("ETag"))
+	t.Assert().Equal(http.StatusNotFound, s.do(http.MethodGet, deleted, "").Code)
+}
+
+func (s *PersonHandlerTestSuite) TestBatchAtomic(t provider.T) {
+	path := s.create(t, `{"name":"Name","age":20}`)
+
+	w := s.do(http.MethodPost, "/api/v1/persons:batch", `{"operations":[
+		{"op":"create","data":{"name":"Created","age":30}},
+		{"op":"update","id":1,"data":{"age":21}},
+		{"op":"delete","id":100}
+	]}`)
+	t.Require().Equal(http.StatusMultiStatus, w.Code, w.Body.String())
+
+	var resp batchResponse
+	decode(t, w, &resp)
+	t.Require().Len(resp.Results, 3)
+	t.Assert().Equal(http.StatusFailedDependency, resp.Results[0].Status)
+	t.Assert().Equal(http.StatusFailedDependency, resp.Results[1].Status)
+	t.Assert().Equal(http.StatusNotFound, resp.Results[2].Status)
+
+	t.Assert().Equal(etag(1), s.do(http.MethodGet, path, "").Header().Get("ETag"))
+	t.Assert().Equal(http.StatusNotFound, s.do(http.MethodGet, "/api/v1/persons/2", "").Code)
+
+	w = s.do(http.MethodPost, "/api/v1/persons:batch", `{"operations":[
+		{"op":"create","data":{"name":"Created","age":30}},
+		{"op":"update","id":1,"data":{"age":21}}
+	]}`)
+	t.Assert().Equal(http.StatusOK, w.Code, w.Body.String())
+}
@@ -1,21 +1,28 @@
 package delivery
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	personUseCase "github.com/Davmie/person_service/internal/person/usecase"
 	"io"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/Davmie/person_service/models"
 	"github.com/Davmie/person_service/pkg/logger"
-	//"github.com/asaskevich/govalidator"
+	"github.com/Davmie/person_service/pkg/response"
+	"github.com/Davmie/person_service/pkg/validator"
 )
 
 type PersonHandler struct {
 	PersonUseCase personUseCase.PersonUseCaseI
 	Logger        logger.Logger
+	// RequireIfMatch rejects writes without an If-Match header with 428.
+	RequireIfMatch bool
+	// PutCreates lets Replace create a person at an ID that does not exist.
+	PutCreates bool
 }
 
 func (ah *PersonHandler) Create(w http.ResponseWriter, r *http.Request) {
@@ -23,239 +30,474 @@ func (ah *PersonHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		ah.Logger.Errorw("can`t read body of request",
+		ah.logger(r.Context()).Errorw("can`t read body of request",
 			"err:", err.Error())
-		http.Error(w, "bad data", http.StatusBadRequest)
+		response.Message(w, ah.logger(r.Context()), http.StatusBadRequest, "bad data")
 		return
 	}
 
 	err = r.Body.Close()
 	if err != nil {
-		ah.Logger.Errorw("can`t close body of request", "err:", err.Error())
-		http.Error(w, "close error", http.StatusInternalServerError)
+		ah.logger(r.Context()).Errorw("can`t close body of request", "err:", err.Error())
+		response.Message(w, ah.logger(r.Context()), http.StatusInternalServerError, "close error")
 		return
 	}
 
 	err = json.Unmarshal(body, &person)
 	if err != nil {
-		ah.Logger.Infow("can`t unmarshal form",
+		ah.logger(r.Context()).Infow("can`t unmarshal form",
 			"err:", err.Error())
-		http.Error(w, "bad  data", http.StatusBadRequest)
+		response.Message(w, ah.logger(r.Context()), http.StatusBadRequest, "bad data")
 		return
 	}
 
-	//_, err = govalidator.ValidateStruct(person)
-	//if err != nil {
-	//	ah.Logger.Infow("can`t validate form",
-	//		"err:", err.Error())
-	//	http.Error(w, "bad data", http.StatusBadRequest)
-	//	return
-	//}
+	if errs := person.Validate(); errs != nil {
+		ah.logger(r.Context()).Infow("can`t validate form",
+			"err:", errs.Error())
+		response.Validation(w, ah.logger(r.Context()), errs)
+		return
+	}
 
-	err = ah.PersonUseCase.Create(&person)
+	// The ID and version are assigned by the repository. Clients pick IDs
+	// with PUT, and only when put_creates allows it.
+	person.ID, person.Version = 0, 0
+	err = ah.PersonUseCase.Create(r.Context(), &person)
 	if err != nil {
-		ah.Logger.Infow("can`t create person",
+		ah.logger(r.Context()).Infow("can`t create person",
 			"err:", err.Error())
-		http.Error(w, "can`t create person", http.StatusBadRequest)
+		response.Error(w, ah.logger(r.Context()), err)
 		return
 	}
 
-	//resp, err := json.Marshal(person)
-	//
-	//if err != nil {
-	//	ah.Logger.Errorw("can`t marshal person",
-	//		"err:", err.Error())
-	//	http.Error(w, "can`t make person", http.StatusInternalServerError)
-	//	return
-	//}
-
 	w.Header().Set("Location", fmt.Sprintf("/api/v1/persons/%d", person.ID))
+	w.Header().Set("ETag", person.ETag())
 	w.WriteHeader(http.StatusCreated)
-
-	//_, err = w.Write(resp)
-	//if err != nil {
-	//	ah.Logger.Errorw("can`t write response",
-	//		"err:", err.Error())
-	//	http.Error(w, "can`t write response", http.StatusInternalServerError)
-	//	return
-	//}
 }
 
 func (ah *PersonHandler) Get(w http.ResponseWriter, r *http.Request) {
-	personIdString := r.PathValue("personId")
-	if personIdString == "" {
-		ah.Logger.Errorw("no personId var")
-		http.Error(w, "unknown error", http.StatusInternalServerError)
+	personId, ok := ah.personID(w, r)
+	if !ok {
 		return
 	}
 
-	personId, err := strconv.Atoi(personIdString)
-	if err != nil {
-		ah.Logger.Errorw("fail to convert id to int",
-			"err:", err.Error())
-		http.Error(w, "unknown error", http.StatusInternalServerError)
+	if r.URL.Query().Has("as_of") {
+		ah.getAsOf(w, r, personId)
 		return
 	}
 
-	person, err := ah.PersonUseCase.Get(personId)
+	person, err := ah.PersonUseCase.Get(r.Context(), personId)
 	if err != nil {
-		ah.Logger.Infow("can`t get person",
+		ah.logger(r.Context()).Infow("can`t get person",
 			"err:", err.Error())
-		http.Error(w, "can`t get person", http.StatusNotFound)
+		response.Error(w, ah.logger(r.Context()), err)
 		return
 	}
 
-	resp, err := json.Marshal(person)
+	w.Header().Set("ETag", person.ETag())
+	if notModified(r, person) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
 
+	response.JSON(w, ah.logger(r.Context()), http.StatusOK, person)
+}
+
+// getAsOf responds with the person as it was at the as_of time. Past
+// versions carry no ETag, as they can't be the target of a write.
+func (ah *PersonHandler) getAsOf(w http.ResponseWriter, r *http.Request, personId int) {
+	at, err := time.Parse(time.RFC3339, r.URL.Query().Get("as_of"))
 	if err != nil {
-		ah.Logger.Errorw("can`t marshal person",
+		ah.logger(r.Context()).Infow("can`t parse as_of",
 			"err:", err.Error())
-		http.Error(w, "can`t make person", http.StatusInternalServerError)
+		response.Validation(w, ah.logger(r.Context()), validator.Errors{"as_of": "must be an RFC 3339 time"})
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-
-	_, err = w.Write(resp)
+	person, err := ah.PersonUseCase.GetAsOf(r.Context(), personId, at)
 	if err != nil {
-		ah.Logger.Errorw("can`t write response",
+		ah.logger(r.Context()).Infow("can`t get person as of time",
 			"err:", err.Error())
-		http.Error(w, "can`t write response", http.StatusInternalServerError)
+		response.Error(w, ah.logger(r.Context()), err)
 		return
 	}
+
+	response.JSON(w, ah.logger(r.Context()), http.StatusOK, person)
 }
 
+// Update applies a JSON Merge Patch or, with Content-Type
+// application/json-patch+json, a JSON Patch and responds with the stored
+// person.
 func (ah *PersonHandler) Update(w http.ResponseWriter, r *http.Request) {
-	personIdString := r.PathValue("personId")
-	if personIdString == "" {
-		ah.Logger.Errorw("no personId var")
-		http.Error(w, "unknown error", http.StatusInternalServerError)
+	personId, ok := ah.personID(w, r)
+	if !ok {
 		return
 	}
 
-	personId, err := strconv.Atoi(personIdString)
+	mediaType, err := patchMediaType(r)
 	if err != nil {
-		ah.Logger.Errorw("fail to convert id to int",
+		ah.logger(r.Context()).Infow("unsupported patch type",
 			"err:", err.Error())
-		http.Error(w, "unknown error", http.StatusInternalServerError)
+		w.Header().Set("Accept-Patch", acceptPatch)
+		response.Message(w, ah.logger(r.Context()), http.StatusUnsupportedMediaType, "unsupported media type")
 		return
 	}
 
-	person := &models.Person{}
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		ah.Logger.Errorw("can`t read body of request",
+		ah.logger(r.Context()).Errorw("can`t read body of request",
 			"err:", err.Error())
-		http.Error(w, "bad data", http.StatusBadRequest)
+		response.Message(w, ah.logger(r.Context()), http.StatusBadRequest, "bad data")
 		return
 	}
 
 	err = r.Body.Close()
 	if err != nil {
-		ah.Logger.Errorw("can`t close body of request", "err:", err.Error())
-		http.Error(w, "close error", http.StatusInternalServerError)
+		ah.logger(r.Context()).Errorw("can`t close body of request", "err:", err.Error())
+		response.Message(w, ah.logger(r.Context()), http.StatusInternalServerError, "close error")
 		return
 	}
 
-	err = json.Unmarshal(body, person)
+	version, err := ah.expectedVersion(r, personId)
 	if err != nil {
-		ah.Logger.Infow("can`t unmarshal form",
+		ah.logger(r.Context()).Infow("can`t update person",
 			"err:", err.Error())
-		http.Error(w, "bad data", http.StatusBadRequest)
+		response.Error(w, ah.logger(r.Context()), err)
 		return
 	}
 
-	//_, err = govalidator.ValidateStruct(person)
-	//if err != nil {
-	//	ah.Logger.Infow("can`t validate form",
-	//		"err:", err.Error())
-	//	http.Error(w, "bad data", http.StatusBadRequest)
-	//	return
-	//}
+	var person *models.Person
+	if mediaType == jsonPatchType {
+		var ops models.JSONPatch
+		err = json.Unmarshal(body, &ops)
+		if err != nil {
+			ah.logger(r.Context()).Infow("can`t unmarshal patch",
+				"err:", err.Error())
+			response.Message(w, ah.logger(r.Context()), http.StatusBadRequest, "bad data")
+			return
+		}
+
+		person, err = ah.PersonUseCase.ApplyPatch(r.Context(), personId, version, ops)
+	} else {
+		patch := &models.PersonPatch{}
+		err = json.Unmarshal(body, patch)
+		if err != nil {
+			ah.logger(r.Context()).Infow("can`t unmarshal form",
+				"err:", err.Error())
+			response.Message(w, ah.logger(r.Context()), http.StatusBadRequest, "bad data")
+			return
+		}
+
+		if errs := patch.Validate(); errs != nil {
+			ah.logger(r.Context()).Infow("can`t validate form",
+				"err:", errs.Error())
+			response.Validation(w, ah.logger(r.Context()), errs)
+			return
+		}
+
+		patch.ID = personId
+		patch.Version = version
+		person, err = ah.PersonUseCase.Update(r.Context(), patch)
+	}
+	if err != nil {
+		ah.logger(r.Context()).Infow("can`t update person",
+			"err:", err.Error())
+		response.Error(w, ah.logger(r.Context()), err)
+		return
+	}
 
-	person.ID = personId
-	err = ah.PersonUseCase.Update(person)
+	w.Header().Set("ETag", person.ETag())
+	response.JSON(w, ah.logger(r.Context()), http.StatusOK, person)
+}
+
+// Replace overwrites the person with the request body. Omitted fields are
+// reset to their zero values.
+func (ah *PersonHandler) Replace(w http.ResponseWriter, r *http.Request) {
+	personId, ok := ah.personID(w, r)
+	if !ok {
+		return
+	}
+
+	person := &models.Person{}
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		ah.Logger.Infow("can`t update person",
+		ah.logger(r.Context()).Errorw("can`t read body of request",
 			"err:", err.Error())
-		http.Error(w, "can`t update person", http.StatusNotFound)
+		response.Message(w, ah.logger(r.Context()), http.StatusBadRequest, "bad data")
 		return
 	}
 
-	resp, err := json.Marshal(person)
+	err = r.Body.Close()
+	if err != nil {
+		ah.logger(r.Context()).Errorw("can`t close body of request", "err:", err.Error())
+		response.Message(w, ah.logger(r.Context()), http.StatusInternalServerError, "close error")
+		return
+	}
 
+	err = json.Unmarshal(body, person)
 	if err != nil {
-		ah.Logger.Errorw("can`t marshal person",
+		ah.logger(r.Context()).Infow("can`t unmarshal form",
 			"err:", err.Error())
-		http.Error(w, "can`t make person", http.StatusInternalServerError)
+		response.Message(w, ah.logger(r.Context()), http.StatusBadRequest, "bad data")
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	if errs := person.Validate(); errs != nil {
+		ah.logger(r.Context()).Infow("can`t validate form",
+			"err:", errs.Error())
+		response.Validation(w, ah.logger(r.Context()), errs)
+		return
+	}
 
-	_, err = w.Write(resp)
+	person.ID = personId
+	var created bool
+	person.Version, err = ah.expectedVersion(r, personId)
+	if err == nil {
+		created, err = ah.PersonUseCase.Replace(r.Context(), person, ah.PutCreates)
+	}
 	if err != nil {
-		ah.Logger.Errorw("can`t write response",
+		ah.logger(r.Context()).Infow("can`t replace person",
 			"err:", err.Error())
-		http.Error(w, "can`t write response", http.StatusInternalServerError)
+		response.Error(w, ah.logger(r.Context()), err)
 		return
 	}
+
+	status := http.StatusOK
+	if created {
+		w.Header().Set("Location", fmt.Sprintf("/api/v1/persons/%d", person.ID))
+		status = http.StatusCreated
+	}
+	w.Header().Set("ETag", person.ETag())
+	response.JSON(w, ah.logger(r.Context()), status, person)
 }
 
 func (ah *PersonHandler) Delete(w http.ResponseWriter, r *http.Request) {
-	personIdString := r.PathValue("personId")
-	if personIdString == "" {
-		ah.Logger.Errorw("no personId var")
-		http.Error(w, "unknown error", http.StatusInternalServerError)
+	personId, ok := ah.personID(w, r)
+	if !ok {
 		return
 	}
 
-	personId, err := strconv.Atoi(personIdString)
+	version, err := ah.expectedVersion(r, personId)
+	if err == nil {
+		err = ah.PersonUseCase.Delete(r.Context(), personId, version)
+	}
 	if err != nil {
-		ah.Logger.Errorw("fail to convert id to int",
+		ah.logger(r.Context()).Infow("can`t delete person",
 			"err:", err.Error())
-		http.Error(w, "unknown error", http.StatusInternalServerError)
+		response.Error(w, ah.logger(r.Context()), err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetAll streams the page of persons as a JSON array while the repository
+// reads it. Errors after the first byte can only be logged, the array is
+// then left unterminated.
+func (ah *PersonHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	q, ok := ah.pageQuery(w, r, false)
+	if !ok {
 		return
 	}
 
-	err = ah.PersonUseCase.Delete(personId)
+	arr := newJSONArray(w)
+	rc := http.NewResponseController(w)
+	started := false
+	err := ah.PersonUseCase.EachPage(r.Context(), q, func(page *models.PersonPage) error {
+		ah.pageHeaders(w, r, q, page)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		started = true
+		return nil
+	}, func(p *models.Person) error {
+		if err := arr.Write(p); err != nil {
+			return err
+		}
+		if arr.n%flushEvery == 0 {
+			_ = rc.Flush()
+		}
+		return nil
+	})
+	if err != nil && !started {
+		ah.logger(r.Context()).Infow("can`t get all persons",
+			"err:", err.Error())
+		response.Error(w, ah.logger(r.Context()), err)
+		return
+	}
+	if err == nil {
+		err = arr.Close()
+	}
 	if err != nil {
-		ah.Logger.Infow("can`t delete person",
+		ah.logger(r.Context()).Errorw("list cut short",
+			"listed:", arr.n,
 			"err:", err.Error())
-		http.Error(w, "can`t delete person", http.StatusNotFound)
+	}
+}
+
+// Trash lists the deleted persons that can still be restored, with the time
+// they were deleted.
+func (ah *PersonHandler) Trash(w http.ResponseWriter, r *http.Request) {
+	page, ok := ah.page(w, r, true)
+	if !ok {
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	persons := make([]*models.TrashedPerson, 0, len(page.Persons))
+	for _, p := range page.Persons {
+		persons = append(persons, models.NewTrashedPerson(p))
+	}
+
+	response.JSON(w, ah.logger(r.Context()), http.StatusOK, persons)
 }
 
-func (ah *PersonHandler) GetAll(w http.ResponseWriter, r *http.Request) {
-	persons, err := ah.PersonUseCase.GetAll()
+func (ah *PersonHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	personId, ok := ah.personID(w, r)
+	if !ok {
+		return
+	}
+
+	person, err := ah.PersonUseCase.Restore(r.Context(), personId)
 	if err != nil {
-		ah.Logger.Infow("can`t get all persons",
+		ah.logger(r.Context()).Infow("can`t restore person",
 			"err:", err.Error())
-		http.Error(w, "can`t get all persons", http.StatusInternalServerError)
+		response.Error(w, ah.logger(r.Context()), err)
+		return
+	}
+
+	w.Header().Set("ETag", person.ETag())
+	response.JSON(w, ah.logger(r.Context()), http.StatusOK, person)
+}
+
+// Revert writes the fields of the version given in the query back to the
+// person as a new version.
+func (ah *PersonHandler) Revert(w http.ResponseWriter, r *http.Request) {
+	personId, ok := ah.personID(w, r)
+	if !ok {
 		return
 	}
 
-	resp, err := json.Marshal(persons)
+	version, err := strconv.Atoi(r.URL.Query().Get("version"))
+	if err != nil || version < 1 {
+		ah.logger(r.Context()).Infow("bad version to revert to",
+			"version:", r.URL.Query().Get("version"))
+		response.Validation(w, ah.logger(r.Context()), validator.Errors{"version": "must be a positive integer"})
+		return
+	}
+
+	var person *models.Person
+	expected, err := ah.expectedVersion(r, personId)
+	if err == nil {
+		person, err = ah.PersonUseCase.Revert(r.Context(), personId, version, expected)
+	}
 	if err != nil {
-		ah.Logger.Errorw("can`t marshal person",
+		ah.logger(r.Context()).Infow("can`t revert person",
 			"err:", err.Error())
-		http.Error(w, "can`t make person", http.StatusInternalServerError)
+		response.Error(w, ah.logger(r.Context()), err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	w.Header().Set("ETag", person.ETag())
+	response.JSON(w, ah.logger(r.Context()), http.StatusOK, person)
+}
+
+// History lists the audit trail of a person, newest first. It is kept after
+// the person is purged.
+func (ah *PersonHandler) History(w http.ResponseWriter, r *http.Request) {
+	personId, ok := ah.personID(w, r)
+	if !ok {
+		return
+	}
 
-	_, err = w.Write(resp)
+	q, errs := parseAuditQuery(r.URL.Query())
+	if errs != nil {
+		ah.logger(r.Context()).Infow("can`t parse query",
+			"err:", errs.Error())
+		response.Validation(w, ah.logger(r.Context()), errs)
+		return
+	}
+	q.PersonID = personId
+
+	page, err := ah.PersonUseCase.History(r.Context(), q)
 	if err != nil {
-		ah.Logger.Errorw("can`t write response",
+		ah.logger(r.Context()).Infow("can`t get person history",
 			"err:", err.Error())
-		http.Error(w, "can`t write response", http.StatusInternalServerError)
+		response.Error(w, ah.logger(r.Context()), err)
 		return
 	}
+
+	w.Header().Set("X-Total-Count", strconv.FormatInt(page.Total, 10))
+	if links := historyLinks(r, q, page); links != "" {
+		w.Header().Set("Link", links)
+	}
+
+	entries := page.Entries
+	if entries == nil {
+		entries = []*models.AuditEntry{}
+	}
+
+	response.JSON(w, ah.logger(r.Context()), http.StatusOK, entries)
+}
+
+// page runs the listing query of r and sets the X-Total-Count and Link
+// headers. It writes the error response itself and then returns false.
+func (ah *PersonHandler) page(w http.ResponseWriter, r *http.Request, trashed bool) (*models.PersonPage, bool) {
+	q, ok := ah.pageQuery(w, r, trashed)
+	if !ok {
+		return nil, false
+	}
+
+	page, err := ah.PersonUseCase.GetAll(r.Context(), q)
+	if err != nil {
+		ah.logger(r.Context()).Infow("can`t get all persons",
+			"err:", err.Error())
+		response.Error(w, ah.logger(r.Context()), err)
+		return nil, false
+	}
+
+	ah.pageHeaders(w, r, q, page)
+	return page, true
+}
+
+func (ah *PersonHandler) pageQuery(w http.ResponseWriter, r *http.Request, trashed bool) (models.PersonQuery, bool) {
+	q, errs := parsePersonQuery(r.URL.Query())
+	if errs != nil {
+		ah.logger(r.Context()).Infow("can`t parse query",
+			"err:", errs.Error())
+		response.Validation(w, ah.logger(r.Context()), errs)
+		return q, false
+	}
+	q.Trashed = trashed
+
+	return q, true
+}
+
+// pageHeaders sets the total and the links to the neighbouring pages.
+func (ah *PersonHandler) pageHeaders(w http.ResponseWriter, r *http.Request, q models.PersonQuery, page *models.PersonPage) {
+	w.Header().Set("X-Total-Count", strconv.FormatInt(page.Total, 10))
+	if links := pageLinks(r, q, page); links != "" {
+		w.Header().Set("Link", links)
+	}
+}
+
+func (ah *PersonHandler) personID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	personIdString := r.PathValue("personId")
+	if personIdString == "" {
+		ah.logger(r.Context()).Errorw("no personId var")
+		response.Message(w, ah.logger(r.Context()), http.StatusInternalServerError, "unknown error")
+		return 0, false
+	}
+
+	personId, err := strconv.Atoi(personIdString)
+	if err != nil {
+		ah.logger(r.Context()).Infow("fail to convert id to int",
+			"err:", err.Error())
+		response.Message(w, ah.logger(r.Context()), http.StatusBadRequest, "bad person id")
+		return 0, false
+	}
+
+	return personId, true
+}
+
+// logger returns the request-scoped logger, which carries the request id.
+func (ah *PersonHandler) logger(ctx context.Context) logger.Logger {
+	return logger.FromContext(ctx, ah.Logger)
 }
@@ -0,0 +1,150 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	memPerson "github.com/Davmie/person_service/internal/person/repository/memory"
+	personMocks "github.com/Davmie/person_service/internal/person/repository/mocks"
+	personUseCase "github.com/Davmie/person_service/internal/person/usecase"
+	"github.com/Davmie/person_service/models"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+type PersonHandlerTestSuite struct {
+	suite.Suite
+	handler *PersonHandler
+	router  *http.ServeMux
+}
+
+func TestPersonHandlerSuite(t *testing.T) {
+	suite.RunSuite(t, new(PersonHandlerTestSuite))
+}
+
+func (s *PersonHandlerTestSuite) BeforeEach(t provider.T) {
+	s.handler = &PersonHandler{
+		PersonUseCase: personUseCase.New(memPerson.New()),
+		Logger:        zap.NewNop().Sugar(),
+	}
+
+	s.router = http.NewServeMux()
+	s.router.HandleFunc("GET /api/v1/persons/{personId}", s.handler.Get)
+	s.router.HandleFunc("GET /api/v1/persons", s.handler.GetAll)
+	s.router.HandleFunc("POST /api/v1/persons", s.handler.Create)
+	s.router.HandleFunc("POST /api/v1/persons:batch", s.handler.Batch)
+	s.router.HandleFunc("GET /api/v1/persons/export", s.handler.Export)
+	s.router.HandleFunc("POST /api/v1/persons/import", s.handler.Import)
+	s.router.HandleFunc("PUT /api/v1/persons/{personId}", s.handler.Replace)
+	s.router.HandleFunc("PATCH /api/v1/persons/{personId}", s.handler.Update)
+	s.router.HandleFunc("DELETE /api/v1/persons/{personId}", s.handler.Delete)
+}
+
+// do serves a request with the given headers, passed as name and value
+// pairs.
+func (s *PersonHandlerTestSuite) do(method, target, body string, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	return w
+}
+
+// create stores a person through the API and returns its path.
+func (s *PersonHandlerTestSuite) create(t provider.T, body string) string {
+	w := s.do(http.MethodPost, "/api/v1/persons", body)
+	t.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+
+	return w.Header().Get("Location")
+}
+
+// decode unmarshals the body of a response into v.
+func decode(t provider.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Require().NoError(json.Unmarshal(w.Body.Bytes(), v), w.Body.String())
+}
+
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+func (s *PersonHandlerTestSuite) TestCreateIgnoresID(t provider.T) {
+	repo := personMocks.NewPersonRepositoryI(t)
+	repo.On("Create", mock.Anything, mock.MatchedBy(func(p *models.Person) bool {
+		return p.ID == 0 && p.Version == 0
+	})).Run(func(args mock.Arguments) {
+		p := args.Get(1).(*models.Person)
+		p.ID, p.Version = 1, 1
+	}).Return(nil).Once()
+	s.handler.PersonUseCase = personUseCase.New(repo)
+
+	path := s.create(t, `{"id":42,"name":"Name","age":20,"version":7}`)
+	t.Assert().Equal("/api/v1/persons/1", path)
+}
+
+func (s *PersonHandlerTestSuite) TestGetAll(t provider.T) {
+	s.create(t, `{"name":"Alice","age":30}`)
+	s.create(t, `{"name":"Bob","age":20}`)
+	s.create(t, `{"name":"Carol","age":40}`)
+
+	w := s.do(http.MethodGet, "/api/v1/persons?limit=2&sort=-age", "")
+	t.Require().Equal(http.StatusOK, w.Code)
+	t.Assert().Equal("application/json", w.Header().Get("Content-Type"))
+	t.Assert().Equal("3", w.Header().Get("X-Total-Count"))
+	var persons []models.Person
+	decode(t, w, &persons)
+	t.Require().Len(persons, 2)
+	t.Assert().Equal("Carol", persons[0].Name)
+	t.Assert().Equal("Alice", persons[1].Name)
+
+	link := w.Header().Get("Link")
+	t.Require().True(strings.HasPrefix(link, "<"), link)
+	next := link[1:strings.Index(link, ">")]
+	t.Assert().Contains(link, `rel="next"`)
+
+	w = s.do(http.MethodGet, next, "")
+	t.Require().Equal(http.StatusOK, w.Code)
+	decode(t, w, &persons)
+	t.Require().Len(persons, 1)
+	t.Assert().Equal("Bob", persons[0].Name)
+	t.Assert().Contains(w.Header().Get("Link"), `rel="prev"`)
+	t.Assert().NotContains(w.Header().Get("Link"), `rel="next"`)
+
+	w = s.do(http.MethodGet, "/api/v1/persons?age_gte=99", "")
+	t.Require().Equal(http.StatusOK, w.Code)
+	t.Assert().Equal("[]\n", w.Body.String())
+
+	w = s.do(http.MethodGet, "/api/v1/persons?limit=x", "")
+	t.Assert().Equal(http.StatusBadRequest, w.Code)
+}
+
+func (s *PersonHandlerTestSuite) TestGetAllFails(t provider.T) {
+	repo := personMocks.NewPersonRepositoryI(t)
+	s.handler.PersonUseCase = personUseCase.New(repo)
+
+	repo.On("EachPage", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(models.ErrUnavailable).Once()
+	w := s.do(http.MethodGet, "/api/v1/persons", "")
+	t.Assert().Equal(http.StatusServiceUnavailable, w.Code)
+
+	repo.On("EachPage", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(func(_ context.Context, _ models.PersonQuery, start func(*models.PersonPage) error, fn func(*models.Person) error) error {
+			t.Require().NoError(start(&models.PersonPage{Total: 2}))
+			t.Require().NoError(fn(&models.Person{ID: 1, Name: "Name"}))
+			return models.ErrUnavailable
+		}).Once()
+	w = s.do(http.MethodGet, "/api/v1/persons", "")
+	t.Assert().Equal(http.StatusOK, w.Code)
+	t.Assert().Equal("2", w.Header().Get("X-Total-Count"))
+	t.Assert().Equal(`[{"id":1,"name":"Name","age":0,"address":"","work":"","version":0}`+"\n", w.Body.String())
+}
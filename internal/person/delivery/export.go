@@ -0,0 +1,195 @@
+package delivery
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Davmie/person_service/models"
+	"github.com/Davmie/person_service/pkg/response"
+	"github.com/Davmie/person_service/pkg/validator"
+)
+
+const (
+	formatCSV    = "csv"
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+
+	csvMediaType    = "text/csv"
+	ndjsonMediaType = "application/x-ndjson"
+)
+
+// flushEvery is the number of persons a streamed response, a list or an
+// export, writes between flushes, so that the client receives it while it
+// is read.
+const flushEvery = 500
+
+// csvColumns is the header of an export, and the columns an import accepts.
+var csvColumns = []string{"id", "name", "age", "address", "work", "version"}
+
+// pagingParams are the list parameters an export refuses: it always returns
+// every match in ID order.
+var pagingParams = []string{"limit", "offset", "cursor", "sort"}
+
+// formulaPrefixes start cells that spreadsheets evaluate as formulas.
+const formulaPrefixes = "=+-@\t\r"
+
+// csvEscaped reports whether a cell starts with a character csvCell
+// escapes: a formula prefix or the quote itself, so that unescaping is
+// unambiguous.
+func csvEscaped(value string) bool {
+	return value != "" && (value[0] == '\'' || strings.ContainsRune(formulaPrefixes, rune(value[0])))
+}
+
+// csvCell quotes a value that a spreadsheet would run as a formula, or that
+// starts with a quote already, by prefixing it with a single quote. Imports
+// strip the prefix again.
+func csvCell(value string) string {
+	if csvEscaped(value) {
+		return "'" + value
+	}
+
+	return value
+}
+
+// csvValue reverses csvCell.
+func csvValue(cell string) string {
+	if len(cell) > 1 && cell[0] == '\'' && csvEscaped(cell[1:]) {
+		return cell[1:]
+	}
+
+	return cell
+}
+
+// jsonArray writes values as the elements of a JSON array, one at a time.
+type jsonArray struct {
+	w   io.Writer
+	enc *json.Encoder
+	n   int
+}
+
+func newJSONArray(w io.Writer) *jsonArray {
+	return &jsonArray{w: w, enc: json.NewEncoder(w)}
+}
+
+func (a *jsonArray) Write(v interface{}) error {
+	sep := ","
+	if a.n == 0 {
+		sep = "["
+	}
+	if _, err := io.WriteString(a.w, sep); err != nil {
+		return err
+	}
+
+	a.n++
+	return a.enc.Encode(v)
+}
+
+// Close ends the array, opening it first if nothing was written.
+func (a *jsonArray) Close() error {
+	end := "]\n"
+	if a.n == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(a.w, end)
+	return err
+}
+
+// Export streams the persons matching the list filters as CSV, a JSON array
+// or NDJSON, so that memory use does not grow with the number of persons.
+// Errors after the first byte can only be logged, the response is then cut
+// short and a JSON array is left unterminated.
+func (ah *PersonHandler) Export(w http.ResponseWriter, r *http.Request) {
+	q, errs := parsePersonQuery(r.URL.Query())
+	if errs == nil {
+		errs = validator.Errors{}
+	}
+	for _, param := range pagingParams {
+		if r.URL.Query().Has(param) {
+			errs[param] = "is not supported, exports hold all matches in ID order"
+		}
+	}
+	format := r.URL.Query().Get("format")
+	if format != formatCSV && format != formatJSON && format != formatNDJSON {
+		errs["format"] = "must be csv, json or ndjson"
+	}
+	if len(errs) == 0 {
+		errs = nil
+	}
+	if errs != nil {
+		ah.logger(r.Context()).Infow("can`t parse query",
+			"err:", errs.Error())
+		response.Validation(w, ah.logger(r.Context()), errs)
+		return
+	}
+
+	var (
+		write  func(p *models.Person) error
+		flush  func() error
+		finish func() error
+	)
+	switch format {
+	case formatCSV:
+		cw := csv.NewWriter(w)
+		// The header is buffered until the first flush, like the rows.
+		_ = cw.Write(csvColumns)
+		write = func(p *models.Person) error {
+			return cw.Write([]string{
+				strconv.Itoa(p.ID), csvCell(p.Name), strconv.Itoa(p.Age), csvCell(p.Address), csvCell(p.Work), strconv.Itoa(p.Version),
+			})
+		}
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+		finish = flush
+		w.Header().Set("Content-Type", csvMediaType+"; charset=utf-8")
+	case formatJSON:
+		arr := newJSONArray(w)
+		write = func(p *models.Person) error {
+			return arr.Write(p)
+		}
+		flush = func() error { return nil }
+		finish = arr.Close
+		w.Header().Set("Content-Type", "application/json")
+	case formatNDJSON:
+		enc := json.NewEncoder(w)
+		write = func(p *models.Person) error {
+			return enc.Encode(p)
+		}
+		flush = func() error { return nil }
+		finish = flush
+		w.Header().Set("Content-Type", ndjsonMediaType)
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="persons.`+format+`"`)
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+
+	n := 0
+	err := ah.PersonUseCase.Export(r.Context(), q.Filter, func(p *models.Person) error {
+		if err := write(p); err != nil {
+			return err
+		}
+
+		n++
+		if n%flushEvery == 0 {
+			if err := flush(); err != nil {
+				return err
+			}
+			_ = rc.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = finish()
+	}
+	if err != nil {
+		ah.logger(r.Context()).Errorw("export cut short",
+			"exported:", n,
+			"err:", err.Error())
+	}
+}
@@ -0,0 +1,283 @@
+package delivery
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"runtime"
+	"testing"
+
+	memPerson "github.com/Davmie/person_service/internal/person/repository/memory"
+	pgPerson "github.com/Davmie/person_service/internal/person/repository/postgres"
+	personUseCase "github.com/Davmie/person_service/internal/person/usecase"
+	"github.com/Davmie/person_service/models"
+	"github.com/Davmie/person_service/pkg/response"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+func (s *PersonHandlerTestSuite) TestExport(t provider.T) {
+	s.create(t, `{"name":"=HYPERLINK(\"http://evil\")","age":20,"work":"@SUM(1)"}`)
+	s.create(t, `{"name":"Bob","age":30,"address":"-1 Main St"}`)
+
+	w := s.do(http.MethodGet, "/api/v1/persons/export?format=csv", "")
+	t.Require().Equal(http.StatusOK, w.Code)
+	t.Assert().Equal(`id,name,age,address,work,version
+1,"'=HYPERLINK(""http://evil"")",20,,'@SUM(1),1
+2,Bob,30,'-1 Main St,,1
+`, w.Body.String())
+
+	w = s.do(http.MethodGet, "/api/v1/persons/export?format=json&age_gte=25", "")
+	t.Require().Equal(http.StatusOK, w.Code)
+	var persons []models.Person
+	decode(t, w, &persons)
+	t.Assert().Equal([]models.Person{{ID: 2, Name: "Bob", Age: 30, Address: "-1 Main St", Version: 1}}, persons)
+
+	w = s.do(http.MethodGet, "/api/v1/persons/export?format=json&age_gte=99", "")
+	t.Assert().Equal("[]\n", w.Body.String())
+}
+
+func (s *PersonHandlerTestSuite) TestExportRejectsPaging(t provider.T) {
+	w := s.do(http.MethodGet, "/api/v1/persons/export?format=xml&limit=10&sort=name", "")
+	t.Require().Equal(http.StatusBadRequest, w.Code)
+
+	var resp response.ValidationErrorResponse
+	decode(t, w, &resp)
+	t.Assert().Len(resp.Errors, 3)
+	for _, param := range []string{"format", "limit", "sort"} {
+		t.Assert().Contains(resp.Errors, param)
+	}
+}
+
+func (s *PersonHandlerTestSuite) TestExportImportRoundTrip(t provider.T) {
+	values := []string{"'=x", "=x", "'plain", "''", "'", "-1 Main St", "plain"}
+	for _, v := range values {
+		body, err := json.Marshal(models.Person{Name: "Name" + v, Age: 20, Address: v, Work: v})
+		t.Require().NoError(err)
+		s.create(t, string(body))
+	}
+
+	w := s.do(http.MethodGet, "/api/v1/persons/export?format=csv", "")
+	t.Require().Equal(http.StatusOK, w.Code)
+	exported := w.Body.String()
+
+	s.handler.PersonUseCase = personUseCase.New(memPerson.New())
+	report := s.importFile(t, "/api/v1/persons/import", csvMediaType, exported)
+	t.Require().Equal(len(values), report.Imported, exported)
+
+	w = s.do(http.MethodGet, "/api/v1/persons/export?format=json", "")
+	var persons []models.Person
+	decode(t, w, &persons)
+	t.Require().Len(persons, len(values))
+	for i, v := range values {
+		t.Assert().Equal("Name"+v, persons[i].Name)
+		t.Assert().Equal(v, persons[i].Address)
+		t.Assert().Equal(v, persons[i].Work)
+	}
+}
+
+// heapSampleEvery is the number of rows between two heap samples.
+const heapSampleEvery = 10_000
+
+// benchDB is a database/sql connector whose table holds rows persons that
+// are made up as they are read, so that only the code reading them uses
+// memory. It answers the queries of the postgres repository and tracks the
+// peak heap.
+type benchDB struct {
+	rows int
+	read int
+	peak uint64
+}
+
+func (db *benchDB) Connect(context.Context) (driver.Conn, error) { return benchConn{db}, nil }
+func (db *benchDB) Driver() driver.Driver                        { return nil }
+
+func (db *benchDB) sample() {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	db.peak = max(db.peak, ms.HeapInuse)
+}
+
+type benchConn struct {
+	db *benchDB
+}
+
+func (c benchConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c benchConn) Close() error                        { return nil }
+func (c benchConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+// The queries benchConn answers are told apart by their shape only, so that
+// changes to columns, quoting or clause order do not matter.
+var (
+	benchTable   = regexp.MustCompile(`(?i)\bfrom\s+"?people"?`)
+	benchCount   = regexp.MustCompile(`(?i)^select\s+count\(`)
+	benchIDList  = regexp.MustCompile(`(?i)\bid"?\s+in\s*\(`)
+	benchAfterID = regexp.MustCompile(`(?i)\bid"?\s*>\s*\$1\b`)
+)
+
+func (c benchConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	switch {
+	case !benchTable.MatchString(query):
+		return nil, fmt.Errorf("unexpected query %q", query)
+	case benchCount.MatchString(query):
+		return &benchRows{db: c.db, count: true, ids: []int{c.db.rows}}, nil
+	case benchIDList.MatchString(query):
+		// An ID list names the rows of a page.
+		ids := make([]int, 0, len(args))
+		for _, arg := range args {
+			ids = append(ids, int(arg.Value.(int64)))
+		}
+		return &benchRows{db: c.db, ids: ids}, nil
+	}
+
+	// Otherwise rows are read in ID order, after the ID of the last batch,
+	// and the last argument is the limit.
+	after := 0
+	if benchAfterID.MatchString(query) {
+		after = int(args[0].Value.(int64))
+	}
+	limit := int(args[len(args)-1].Value.(int64))
+	return &benchRows{db: c.db, next: after + 1, end: min(after+limit, c.db.rows)}, nil
+}
+
+// benchRows yields the persons listed in ids, or those from next to end.
+type benchRows struct {
+	db        *benchDB
+	count     bool
+	ids       []int
+	next, end int
+}
+
+func (r *benchRows) Columns() []string {
+	if r.count {
+		return []string{"count"}
+	}
+	return []string{"id", "name", "age", "address", "work", "version"}
+}
+
+func (r *benchRows) Close() error { return nil }
+
+func (r *benchRows) Next(dest []driver.Value) error {
+	var id int
+	switch {
+	case r.ids != nil:
+		if len(r.ids) == 0 {
+			return io.EOF
+		}
+		id, r.ids = r.ids[0], r.ids[1:]
+	case r.next <= r.end:
+		id = r.next
+		r.next++
+	default:
+		return io.EOF
+	}
+
+	if r.count {
+		dest[0] = int64(id)
+		return nil
+	}
+	dest[0], dest[1], dest[2], dest[3], dest[4], dest[5] = int64(id), "Name", int64(30), "Address", "Work", int64(1)
+
+	r.db.read++
+	if r.db.read%heapSampleEvery == 0 {
+		r.db.sample()
+	}
+	return nil
+}
+
+// benchHandler serves persons from a postgres repository over db.
+func benchHandler(b *testing.B, db *benchDB) *PersonHandler {
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn:                 sql.OpenDB(db),
+		PreferSimpleProtocol: true,
+	}), &gorm.Config{Logger: gormLogger.Discard})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return &PersonHandler{
+		PersonUseCase: personUseCase.New(pgPerson.New(nil, gormDB)),
+		Logger:        zap.NewNop().Sugar(),
+	}
+}
+
+// discardWriter is a ResponseWriter that drops the body.
+type discardWriter struct {
+	header http.Header
+}
+
+func (dw *discardWriter) Header() http.Header         { return dw.header }
+func (dw *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (dw *discardWriter) WriteHeader(int)             {}
+func (dw *discardWriter) Flush()                      {}
+
+// BenchmarkExport reports the peak heap of an export read through the
+// postgres repository as peak-heap-MB, which stays flat as rows grow. The
+// marshal case encodes the whole list at once, as a single response does,
+// for comparison.
+func BenchmarkExport(b *testing.B) {
+	for _, format := range []string{formatJSON, formatNDJSON, formatCSV, "marshal"} {
+		for _, rows := range []int{10_000, 100_000, 1_000_000} {
+			b.Run(fmt.Sprintf("%s/rows=%d", format, rows), func(b *testing.B) {
+				db := &benchDB{rows: rows}
+				ah := benchHandler(b, db)
+				r := httptest.NewRequest(http.MethodGet, "/api/v1/persons/export?format="+format, nil)
+
+				handle := ah.Export
+				if format == "marshal" {
+					handle = func(w http.ResponseWriter, r *http.Request) {
+						var persons []*models.Person
+						_ = ah.PersonUseCase.Export(r.Context(), models.PersonFilter{}, func(p *models.Person) error {
+							persons = append(persons, p)
+							return nil
+						})
+						response.JSON(w, ah.Logger, http.StatusOK, persons)
+						db.sample()
+					}
+				}
+
+				b.ReportAllocs()
+				for range b.N {
+					runtime.GC()
+					handle(&discardWriter{header: http.Header{}}, r)
+				}
+				if db.read != rows*b.N {
+					b.Fatalf("read %d rows, want %d", db.read, rows*b.N)
+				}
+				b.ReportMetric(float64(db.peak)/(1<<20), "peak-heap-MB")
+			})
+		}
+	}
+}
+
+// BenchmarkGetAll lists a full page out of tables of growing size.
+func BenchmarkGetAll(b *testing.B) {
+	for _, rows := range []int{10_000, 100_000, 1_000_000} {
+		b.Run(fmt.Sprintf("rows=%d", rows), func(b *testing.B) {
+			db := &benchDB{rows: rows}
+			ah := benchHandler(b, db)
+			r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/persons?limit=%d", models.MaxPageLimit), nil)
+
+			b.ReportAllocs()
+			for range b.N {
+				runtime.GC()
+				ah.GetAll(&discardWriter{header: http.Header{}}, r)
+				db.sample()
+			}
+			// The keys of the page and one more, then the page itself.
+			if want := (2*models.MaxPageLimit + 1) * b.N; db.read != want {
+				b.Fatalf("read %d rows, want %d", db.read, want)
+			}
+			b.ReportMetric(float64(db.peak)/(1<<20), "peak-heap-MB")
+		})
+	}
+}
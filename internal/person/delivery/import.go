@@ -0,0 +1,176 @@
+package delivery
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Davmie/person_service/models"
+	"github.com/Davmie/person_service/pkg/response"
+	"github.com/Davmie/person_service/pkg/validator"
+	"github.com/pkg/errors"
+)
+
+// maxNDJSONLine bounds one line of an NDJSON import.
+const maxNDJSONLine = 1 << 20
+
+// Import creates persons from a CSV or NDJSON body, picked by Content-Type,
+// and reports the rows it rejected. With dry_run=true nothing is written.
+// When the body breaks off or can't be parsed, the report of the rows before
+// comes with the error status.
+func (ah *PersonHandler) Import(w http.ResponseWriter, r *http.Request) {
+	dryRun := false
+	if s := r.URL.Query().Get("dry_run"); s != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(s); err != nil {
+			response.Validation(w, ah.logger(r.Context()), validator.Errors{"dry_run": "must be a boolean"})
+			return
+		}
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var (
+		next func() (*models.ImportRow, error)
+		err  error
+	)
+	switch mediaType {
+	case csvMediaType:
+		next, err = csvRows(r.Body)
+	case ndjsonMediaType, "application/ndjson":
+		next = ndjsonRows(r.Body)
+	default:
+		ah.logger(r.Context()).Infow("unsupported import type",
+			"content_type:", r.Header.Get("Content-Type"))
+		response.Message(w, ah.logger(r.Context()), http.StatusUnsupportedMediaType, "unsupported media type")
+		return
+	}
+	if err != nil {
+		ah.logger(r.Context()).Infow("can`t read import header",
+			"err:", err.Error())
+		response.Error(w, ah.logger(r.Context()), err)
+		return
+	}
+
+	report, err := ah.PersonUseCase.Import(r.Context(), next, dryRun)
+	if err != nil {
+		ah.logger(r.Context()).Infow("can`t import persons",
+			"err:", err.Error())
+		if report == nil {
+			response.Error(w, ah.logger(r.Context()), err)
+			return
+		}
+
+		// Rows before the failure may be stored, the report tells which.
+		status, msg := response.Status(err)
+		report.Stopped.Error = msg
+		response.JSON(w, ah.logger(r.Context()), status, report)
+		return
+	}
+
+	response.JSON(w, ah.logger(r.Context()), http.StatusOK, report)
+}
+
+// csvRows reads the header of a CSV import and returns a reader of its rows.
+// The header names the columns, in any order; name is required.
+func csvRows(body io.Reader) (func() (*models.ImportRow, error), error) {
+	reader := csv.NewReader(body)
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.Wrap(models.ErrValidation, "no CSV header")
+	}
+
+	columns := map[string]int{}
+	v := validator.New()
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !slices.Contains(csvColumns, name) {
+			v.Add("header", fmt.Sprintf("unknown column %q", name))
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		v.Add("header", "name column is required")
+	}
+	if errs := v.Errors(); errs != nil {
+		return nil, errs
+	}
+
+	return func() (*models.ImportRow, error) {
+		record, err := reader.Read()
+		var parseErr *csv.ParseError
+		switch {
+		case errors.As(err, &parseErr) && errors.Is(parseErr.Err, csv.ErrFieldCount):
+			return &models.ImportRow{Line: parseErr.StartLine, Errors: validator.Errors{"row": "wrong number of fields"}}, nil
+		case errors.As(err, &parseErr):
+			row := &models.ImportRow{Line: parseErr.StartLine}
+			return row, errors.Wrapf(models.ErrValidation, "line %d: %s", parseErr.StartLine, parseErr.Err)
+		case err != nil:
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		field := func(name string) string {
+			if i, ok := columns[name]; ok {
+				return csvValue(record[i])
+			}
+			return ""
+		}
+
+		row := &models.ImportRow{Line: line, Person: &models.Person{
+			Name:    field("name"),
+			Address: field("address"),
+			Work:    field("work"),
+		}}
+		if age := strings.TrimSpace(field("age")); age != "" {
+			if row.Person.Age, err = strconv.Atoi(age); err != nil {
+				row.Errors = validator.Errors{"age": "must be an integer"}
+			}
+		}
+
+		return row, nil
+	}, nil
+}
+
+// ndjsonRows returns a reader of the persons of an NDJSON import, one JSON
+// object per line. Blank lines are skipped.
+func ndjsonRows(body io.Reader) func() (*models.ImportRow, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxNDJSONLine)
+	line := 0
+
+	return func() (*models.ImportRow, error) {
+		for scanner.Scan() {
+			line++
+			data := scanner.Bytes()
+			if len(strings.TrimSpace(string(data))) == 0 {
+				continue
+			}
+
+			row := &models.ImportRow{Line: line, Person: &models.Person{}}
+			if err := json.Unmarshal(data, row.Person); err != nil {
+				row.Errors = validator.Errors{"row": "malformed JSON"}
+			}
+			return row, nil
+		}
+
+		if err := scanner.Err(); err != nil {
+			if errors.Is(err, bufio.ErrTooLong) {
+				row := &models.ImportRow{Line: line + 1}
+				return row, errors.Wrapf(models.ErrValidation, "line %d is too long", row.Line)
+			}
+			return nil, err
+		}
+
+		return nil, io.EOF
+	}
+}
@@ -0,0 +1,131 @@
+package delivery
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Davmie/person_service/models"
+	"github.com/Davmie/person_service/pkg/response"
+	"github.com/Davmie/person_service/pkg/validator"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+)
+
+// importFile posts body to the import endpoint and decodes the report.
+func (s *PersonHandlerTestSuite) importFile(t provider.T, target, contentType, body string) *models.ImportReport {
+	w := s.do(http.MethodPost, target, body, "Content-Type", contentType)
+	t.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+
+	var report models.ImportReport
+	decode(t, w, &report)
+
+	return &report
+}
+
+func (s *PersonHandlerTestSuite) TestImportCSV(t provider.T) {
+	report := s.importFile(t, "/api/v1/persons/import", "text/csv; charset=utf-8",
+		"\ufeffWork, NAME,age,address,id\n"+
+			"Eng,Ann,30,\"Main St\n2nd floor\",99\n"+
+			"QA,Bob,x,,\n"+
+			",,20,,\n"+
+			"'=1+1,Cid,40,,\n"+
+			"Ops,Dan\n")
+
+	t.Assert().Equal(5, report.Rows)
+	t.Assert().Equal(2, report.Imported)
+	t.Assert().Equal([]models.ImportRejection{
+		{Line: 4, Errors: validator.Errors{"age": "must be an integer"}},
+		{Line: 5, Errors: validator.Errors{"name": "is required"}},
+		{Line: 7, Errors: validator.Errors{"row": "wrong number of fields"}},
+	}, report.Rejections)
+
+	var persons []models.Person
+	decode(t, s.do(http.MethodGet, "/api/v1/persons/export?format=json", ""), &persons)
+	t.Assert().Equal([]models.Person{
+		{ID: 1, Name: "Ann", Age: 30, Address: "Main St\n2nd floor", Work: "Eng", Version: 1},
+		{ID: 2, Name: "Cid", Age: 40, Work: "=1+1", Version: 1},
+	}, persons)
+}
+
+func (s *PersonHandlerTestSuite) TestImportCSVHeader(t provider.T) {
+	cases := map[string]struct {
+		Body   string
+		Errors map[string]string
+	}{
+		"unknown column": {
+			Body:   "name,salary\nAnn,100\n",
+			Errors: map[string]string{"header": `unknown column "salary"`},
+		},
+		"no name column": {
+			Body:   "age,work\n30,Eng\n",
+			Errors: map[string]string{"header": "name column is required"},
+		},
+		"no header": {
+			Body: "",
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t provider.T) {
+			w := s.do(http.MethodPost, "/api/v1/persons/import", test.Body, "Content-Type", "text/csv")
+			t.Require().Equal(http.StatusBadRequest, w.Code)
+
+			var resp response.ValidationErrorResponse
+			decode(t, w, &resp)
+			if test.Errors != nil {
+				t.Assert().Equal(test.Errors, resp.Errors)
+			}
+		})
+	}
+}
+
+func (s *PersonHandlerTestSuite) TestImportNDJSON(t provider.T) {
+	report := s.importFile(t, "/api/v1/persons/import", "application/x-ndjson",
+		`{"name":"Ann","age":30}`+"\n"+
+			"\n"+
+			`{"name":"Bob",`+"\n"+
+			`{"name":"Cid","age":200}`+"\n"+
+			`{"id":7,"name":"Dan","age":40}`)
+
+	t.Assert().Equal(4, report.Rows)
+	t.Assert().Equal(2, report.Imported)
+	t.Assert().Equal([]models.ImportRejection{
+		{Line: 3, Errors: validator.Errors{"row": "malformed JSON"}},
+		{Line: 4, Errors: validator.Errors{"age": "must be between 0 and 150"}},
+	}, report.Rejections)
+
+	t.Assert().Equal(http.StatusOK, s.do(http.MethodGet, "/api/v1/persons/2", "").Code)
+	t.Assert().Equal(http.StatusNotFound, s.do(http.MethodGet, "/api/v1/persons/7", "").Code)
+}
+
+func (s *PersonHandlerTestSuite) TestImportDryRun(t provider.T) {
+	report := s.importFile(t, "/api/v1/persons/import?dry_run=true", "text/csv",
+		"name,age\nAnn,30\n,20\nBob,40\n")
+
+	t.Assert().True(report.DryRun)
+	t.Assert().Equal(2, report.Imported)
+	t.Assert().Equal(1, report.Rejected)
+	t.Assert().Equal([]*models.Person{{Name: "Ann", Age: 30}, {Name: "Bob", Age: 40}}, report.Preview)
+
+	w := s.do(http.MethodGet, "/api/v1/persons/export?format=json", "")
+	t.Assert().Equal("[]\n", w.Body.String())
+}
+
+func (s *PersonHandlerTestSuite) TestImportRequest(t provider.T) {
+	w := s.do(http.MethodPost, "/api/v1/persons/import", `{"name":"Ann"}`, "Content-Type", "application/json")
+	t.Assert().Equal(http.StatusUnsupportedMediaType, w.Code)
+
+	w = s.do(http.MethodPost, "/api/v1/persons/import?dry_run=maybe", "name\nAnn\n", "Content-Type", "text/csv")
+	t.Assert().Equal(http.StatusBadRequest, w.Code)
+}
+
+func (s *PersonHandlerTestSuite) TestImportStopped(t provider.T) {
+	body := `{"name":"Ann","age":30}` + "\n" + `{"name":"` + strings.Repeat("a", maxNDJSONLine) + `"}` + "\n"
+	w := s.do(http.MethodPost, "/api/v1/persons/import", body, "Content-Type", "application/x-ndjson")
+	t.Require().Equal(http.StatusBadRequest, w.Code)
+
+	var report models.ImportReport
+	decode(t, w, &report)
+	t.Assert().Equal(1, report.Imported)
+	t.Assert().Equal(&models.ImportStop{Line: 2, Error: "invalid data"}, report.Stopped)
+	t.Assert().Equal(http.StatusOK, s.do(http.MethodGet, "/api/v1/persons/1", "").Code)
+}
@@ -0,0 +1,40 @@
+package delivery
+
+import (
+	"mime"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+
+	// acceptPatch is sent with 415 to list the patch formats PATCH takes.
+	acceptPatch = mergePatchType + ", " + jsonPatchType
+)
+
+// patchMediaType returns the patch format of a PATCH request. Plain JSON and
+// a missing Content-Type are read as a merge patch, which is what clients
+// sent before the patch formats were told apart.
+func patchMediaType(r *http.Request) (string, error) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return mergePatchType, nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", errors.Wrap(err, "bad Content-Type")
+	}
+
+	switch mediaType {
+	case mergePatchType, "application/json":
+		return mergePatchType, nil
+	case jsonPatchType:
+		return jsonPatchType, nil
+	}
+
+	return "", errors.Errorf("unsupported Content-Type %q", mediaType)
+}
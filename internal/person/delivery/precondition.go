@@ -0,0 +1,108 @@
+package delivery
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Davmie/person_service/models"
+)
+
+// expectedVersion turns the If-Match header of a write into the version the
+// write must apply to. 0 means no precondition: the header is missing and
+// not required. "*" gives models.AnyVersion, and without If-Match an
+// If-None-Match of "*" gives models.NoVersion, which lets a PUT create a
+// person when If-Match is required. When If-Match lists several tags, the
+// one of the current version is picked, so that the write still fails if
+// the person changes before it is applied.
+func (ah *PersonHandler) expectedVersion(r *http.Request, personId int) (int, error) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		if strings.TrimSpace(r.Header.Get("If-None-Match")) == "*" {
+			return models.NoVersion, nil
+		}
+		if ah.RequireIfMatch {
+			return 0, models.ErrPreconditionRequired
+		}
+		return 0, nil
+	}
+
+	tags := splitETags(header)
+	if len(tags) == 1 && tags[0] == "*" {
+		return models.AnyVersion, nil
+	}
+
+	var versions []int
+	for _, tag := range tags {
+		if version, ok := parseStrongETag(tag); ok {
+			versions = append(versions, version)
+		}
+	}
+
+	switch len(versions) {
+	case 0:
+		return 0, models.ErrPreconditionFailed
+	case 1:
+		return versions[0], nil
+	}
+
+	current, err := ah.PersonUseCase.Get(r.Context(), personId)
+	if errors.Is(err, models.ErrNotFound) {
+		return 0, models.ErrPreconditionFailed
+	}
+	if err != nil {
+		return 0, err
+	}
+	for _, version := range versions {
+		if version == current.Version {
+			return version, nil
+		}
+	}
+
+	return 0, models.ErrPreconditionFailed
+}
+
+// notModified reports whether the If-None-Match header of a read matches the
+// current ETag, using the weak comparison RFC 9110 prescribes for it.
+func notModified(r *http.Request, person *models.Person) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	current := person.ETag()
+	for _, tag := range splitETags(header) {
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == current {
+			return true
+		}
+	}
+
+	return false
+}
+
+func splitETags(header string) []string {
+	var tags []string
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
+
+// parseStrongETag extracts the version from an ETag made by
+// models.Person.ETag. Weak tags never match a write precondition.
+func parseStrongETag(tag string) (int, bool) {
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+
+	version, err := strconv.Atoi(tag[1 : len(tag)-1])
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+
+	return version, true
+}
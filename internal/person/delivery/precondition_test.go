@@ -0,0 +1,115 @@
+package delivery
+
+import (
+	"net/http"
+
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+)
+
+func (s *PersonHandlerTestSuite) TestIfMatch(t provider.T) {
+	cases := map[string]struct {
+		IfMatch string
+		Status  int
+	}{
+		"current version":   {IfMatch: etag(2), Status: http.StatusOK},
+		"stale version":     {IfMatch: etag(1), Status: http.StatusPreconditionFailed},
+		"list with current": {IfMatch: etag(1) + ", " + etag(2), Status: http.StatusOK},
+		"list without current": {
+			IfMatch: etag(1) + ", " + etag(3),
+			Status:  http.StatusPreconditionFailed,
+		},
+		"any version":   {IfMatch: "*", Status: http.StatusOK},
+		"weak tag":      {IfMatch: "W/" + etag(2), Status: http.StatusPreconditionFailed},
+		"malformed tag": {IfMatch: "2", Status: http.StatusPreconditionFailed},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t provider.T) {
+			path := s.create(t, `{"name":"Name","age":20}`)
+			w := s.do(http.MethodPatch, path, `{"age":21}`)
+			t.Require().Equal(http.StatusOK, w.Code)
+
+			w = s.do(http.MethodPatch, path, `{"age":22}`, "If-Match", test.IfMatch)
+			t.Assert().Equal(test.Status, w.Code, w.Body.String())
+		})
+	}
+}
+
+func (s *PersonHandlerTestSuite) TestIfMatchRequired(t provider.T) {
+	s.handler.RequireIfMatch = true
+	path := s.create(t, `{"name":"Name","age":20}`)
+
+	w := s.do(http.MethodPatch, path, `{"age":21}`)
+	t.Assert().Equal(http.StatusPreconditionRequired, w.Code)
+	w = s.do(http.MethodPut, path, `{"name":"Name","age":21}`)
+	t.Assert().Equal(http.StatusPreconditionRequired, w.Code)
+	w = s.do(http.MethodDelete, path, "")
+	t.Assert().Equal(http.StatusPreconditionRequired, w.Code)
+
+	w = s.do(http.MethodDelete, path, "", "If-Match", etag(1))
+	t.Assert().Equal(http.StatusNoContent, w.Code)
+}
+
+func (s *PersonHandlerTestSuite) TestIfNoneMatch(t provider.T) {
+	path := s.create(t, `{"name":"Name","age":20}`)
+
+	cases := map[string]struct {
+		IfNoneMatch string
+		Status      int
+	}{
+		"current version": {IfNoneMatch: etag(1), Status: http.StatusNotModified},
+		"weak tag":        {IfNoneMatch: "W/" + etag(1), Status: http.StatusNotModified},
+		"list":            {IfNoneMatch: etag(3) + ", " + etag(1), Status: http.StatusNotModified},
+		"any version":     {IfNoneMatch: "*", Status: http.StatusNotModified},
+		"other version":   {IfNoneMatch: etag(2), Status: http.StatusOK},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t provider.T) {
+			w := s.do(http.MethodGet, path, "", "If-None-Match", test.IfNoneMatch)
+			t.Assert().Equal(test.Status, w.Code)
+			t.Assert().Equal(etag(1), w.Header().Get("ETag"))
+			if test.Status == http.StatusNotModified {
+				t.Assert().Empty(w.Body.String())
+			}
+		})
+	}
+}
+
+func (s *PersonHandlerTestSuite) TestIfMatchMissing(t provider.T) {
+	s.handler.PutCreates = true
+
+	cases := map[string]struct {
+		Method  string
+		IfMatch string
+	}{
+		"put any version":   {Method: http.MethodPut, IfMatch: "*"},
+		"put version":       {Method: http.MethodPut, IfMatch: etag(1)},
+		"put list":          {Method: http.MethodPut, IfMatch: etag(1) + ", " + etag(2)},
+		"patch any version": {Method: http.MethodPatch, IfMatch: "*"},
+		"patch version":     {Method: http.MethodPatch, IfMatch: etag(1)},
+		"delete version":    {Method: http.MethodDelete, IfMatch: etag(1)},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t provider.T) {
+			w := s.do(test.Method, "/api/v1/persons/42", `{"name":"Name","age":20}`, "If-Match", test.IfMatch)
+			t.Assert().Equal(http.StatusPreconditionFailed, w.Code, w.Body.String())
+		})
+	}
+
+	w := s.do(http.MethodGet, "/api/v1/persons/42", "")
+	t.Assert().Equal(http.StatusNotFound, w.Code, "nothing was created")
+}
+
+func (s *PersonHandlerTestSuite) TestIfNoneMatchCreates(t provider.T) {
+	s.handler.RequireIfMatch = true
+	s.handler.PutCreates = true
+
+	w := s.do(http.MethodPut, "/api/v1/persons/42", `{"name":"Name","age":20}`, "If-None-Match", "*")
+	t.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+	t.Assert().Equal(etag(1), w.Header().Get("ETag"))
+
+	w = s.do(http.MethodPut, "/api/v1/persons/42", `{"name":"Name","age":21}`, "If-None-Match", "*")
+	t.Assert().Equal(http.StatusPreconditionFailed, w.Code)
+}
@@ -0,0 +1,177 @@
+package delivery
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Davmie/person_service/models"
+	"github.com/Davmie/person_service/pkg/validator"
+)
+
+func parsePersonQuery(values url.Values) (models.PersonQuery, validator.Errors) {
+	q := models.PersonQuery{}
+	v := validator.New()
+
+	intParam := func(name string) *int {
+		s := values.Get(name)
+		if s == "" {
+			return nil
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			v.Add(name, "must be an integer")
+			return nil
+		}
+		return &n
+	}
+
+	if limit := intParam("limit"); limit != nil {
+		q.Limit = *limit
+		v.Range("limit", q.Limit, 1, models.MaxPageLimit)
+	}
+	if offset := intParam("offset"); offset != nil {
+		q.Offset = *offset
+		if q.Offset < 0 {
+			v.Add("offset", "must not be negative")
+		}
+	}
+
+	if sort := values.Get("sort"); sort != "" {
+		for _, field := range strings.Split(sort, ",") {
+			f := models.SortField{Field: strings.TrimSpace(field)}
+			if strings.HasPrefix(f.Field, "-") {
+				f.Field, f.Desc = f.Field[1:], true
+			}
+			if !models.PersonSortFields[f.Field] {
+				v.Add("sort", fmt.Sprintf("unknown field %q", f.Field))
+				continue
+			}
+			q.Sort = append(q.Sort, f)
+		}
+	}
+
+	if cursor := values.Get("cursor"); cursor != "" {
+		c, err := models.DecodeCursor(cursor)
+		switch {
+		case err != nil:
+			v.Add("cursor", "is malformed")
+		case c.Sort != models.SortKey(q.Sort):
+			v.Add("cursor", "does not match sort")
+		case q.Offset != 0:
+			v.Add("cursor", "can`t be combined with offset")
+		default:
+			q.Cursor = c
+		}
+	}
+
+	q.Filter.AgeGte = intParam("age_gte")
+	q.Filter.AgeLte = intParam("age_lte")
+	if values.Has("work") {
+		work := values.Get("work")
+		q.Filter.Work = &work
+	}
+	q.Filter.AddressContains = values.Get("address_contains")
+	q.Filter.NamePrefix = values.Get("name_prefix")
+
+	return q, v.Errors()
+}
+
+// pageLinks builds an RFC 8288 Link header value with next and prev
+// relations. Cursor links are used unless the client paginates by offset.
+func pageLinks(r *http.Request, q models.PersonQuery, page *models.PersonPage) string {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = models.DefaultPageLimit
+	}
+	useOffset := q.Cursor == nil && r.URL.Query().Has("offset")
+
+	link := func(rel string, set func(url.Values)) string {
+		values := r.URL.Query()
+		values.Del("cursor")
+		values.Del("offset")
+		set(values)
+		u := url.URL{Path: r.URL.Path, RawQuery: values.Encode()}
+		return fmt.Sprintf("<%s>; rel=\"%s\"", u.String(), rel)
+	}
+
+	first, last := page.Bounds()
+
+	var links []string
+	if page.HasNext && last != nil {
+		links = append(links, link("next", func(values url.Values) {
+			if useOffset {
+				values.Set("offset", strconv.Itoa(q.Offset+limit))
+				return
+			}
+			values.Set("cursor", models.NewCursor(last, q.Sort, false).Encode())
+		}))
+	}
+	if page.HasPrev {
+		links = append(links, link("prev", func(values url.Values) {
+			if useOffset || first == nil {
+				values.Set("offset", strconv.Itoa(max(q.Offset-limit, 0)))
+				return
+			}
+			values.Set("cursor", models.NewCursor(first, q.Sort, true).Encode())
+		}))
+	}
+
+	return strings.Join(links, ", ")
+}
+
+func parseAuditQuery(values url.Values) (models.AuditQuery, validator.Errors) {
+	q := models.AuditQuery{}
+	v := validator.New()
+
+	if s := values.Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil {
+			v.Add("limit", "must be an integer")
+		} else {
+			q.Limit = limit
+			v.Range("limit", q.Limit, 1, models.MaxPageLimit)
+		}
+	}
+	if s := values.Get("offset"); s != "" {
+		offset, err := strconv.Atoi(s)
+		switch {
+		case err != nil:
+			v.Add("offset", "must be an integer")
+		case offset < 0:
+			v.Add("offset", "must not be negative")
+		default:
+			q.Offset = offset
+		}
+	}
+
+	return q, v.Errors()
+}
+
+// historyLinks builds the Link header value of a history page, which is
+// always paginated by offset.
+func historyLinks(r *http.Request, q models.AuditQuery, page *models.AuditPage) string {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = models.DefaultPageLimit
+	}
+
+	link := func(rel string, offset int) string {
+		values := r.URL.Query()
+		values.Set("offset", strconv.Itoa(offset))
+		u := url.URL{Path: r.URL.Path, RawQuery: values.Encode()}
+		return fmt.Sprintf("<%s>; rel=\"%s\"", u.String(), rel)
+	}
+
+	var links []string
+	if int64(q.Offset+len(page.Entries)) < page.Total {
+		links = append(links, link("next", q.Offset+limit))
+	}
+	if q.Offset > 0 {
+		links = append(links, link("prev", max(q.Offset-limit, 0)))
+	}
+
+	return strings.Join(links, ", ")
+}
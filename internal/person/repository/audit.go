@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Davmie/person_service/models"
+	pkgContext "github.com/Davmie/person_service/pkg/context"
+)
+
+// NewAuditEntry records op on a person, taking the actor and the request id
+// from ctx. before is nil for a created person, after for a purged one.
+func NewAuditEntry(ctx context.Context, op string, before, after *models.Person) *models.AuditEntry {
+	entry := &models.AuditEntry{
+		Operation: op,
+		Changes:   models.Diff(before, after),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if after != nil {
+		entry.PersonID, entry.Version = after.ID, after.Version
+	} else {
+		entry.PersonID, entry.Version = before.ID, before.Version
+	}
+
+	manager := pkgContext.Manager{}
+	if userID, err := manager.UserIDFromContext(ctx); err == nil {
+		entry.ActorID = &userID
+	}
+	entry.RequestID, _ = manager.RequestIDFromContext(ctx)
+
+	return entry
+}
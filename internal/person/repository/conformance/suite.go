@@ -0,0 +1,533 @@
+package conformance
+
+import (
+	"context"
+	"time"
+
+	personRep "github.com/Davmie/person_service/internal/person/repository"
+	"github.com/Davmie/person_service/internal/testBuilders"
+	"github.com/Davmie/person_service/models"
+	pkgContext "github.com/Davmie/person_service/pkg/context"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/pkg/errors"
+)
+
+// PersonRepoSuite checks the behavior every PersonRepositoryI implementation
+// must share. NewRepo is called before each test and must return an empty
+// repository.
+type PersonRepoSuite struct {
+	suite.Suite
+	NewRepo func(t provider.T) personRep.PersonRepositoryI
+
+	repo          personRep.PersonRepositoryI
+	personBuilder *testBuilders.PersonBuilder
+}
+
+func (s *PersonRepoSuite) BeforeEach(t provider.T) {
+	s.repo = s.NewRepo(t)
+	s.personBuilder = testBuilders.NewPersonBuilder()
+}
+
+func (s *PersonRepoSuite) create(t provider.T, name string, age int) models.Person {
+	person := s.personBuilder.
+		WithID(0).
+		WithName(name).
+		WithAge(age).
+		WithAddress("Address").
+		WithWork("Work").
+		Build()
+
+	err := s.repo.Create(context.Background(), &person)
+	t.Require().NoError(err)
+
+	return person
+}
+
+func (s *PersonRepoSuite) TestCreateAssignsID(t provider.T) {
+	first := s.create(t, "First", 20)
+	second := s.create(t, "Second", 30)
+
+	t.Assert().Greater(first.ID, 0)
+	t.Assert().Greater(second.ID, 0)
+	t.Assert().NotEqual(first.ID, second.ID)
+
+	stored, err := s.repo.Get(context.Background(), second.ID)
+	t.Require().NoError(err)
+	t.Assert().Equal(second, *stored)
+}
+
+func (s *PersonRepoSuite) TestNotFound(t provider.T) {
+	ctx := context.Background()
+	missing := s.personBuilder.WithID(100500).WithName("Name").Build()
+
+	_, err := s.repo.Get(ctx, missing.ID)
+	t.Assert().ErrorIs(err, models.ErrNotFound)
+
+	_, err = s.repo.Update(ctx, &models.PersonPatch{ID: missing.ID, Name: &missing.Name})
+	t.Assert().ErrorIs(err, models.ErrNotFound)
+
+	err = s.repo.Delete(ctx, missing.ID, 0)
+	t.Assert().ErrorIs(err, models.ErrNotFound)
+
+	// A write expecting a version cannot apply to a missing person.
+	err = s.repo.Delete(ctx, missing.ID, 1)
+	t.Assert().ErrorIs(err, models.ErrPreconditionFailed)
+
+	_, err = s.repo.Update(ctx, &models.PersonPatch{ID: missing.ID, Name: &missing.Name, Version: models.AnyVersion})
+	t.Assert().ErrorIs(err, models.ErrPreconditionFailed)
+}
+
+func (s *PersonRepoSuite) TestReplacePreconditions(t provider.T) {
+	ctx := context.Background()
+	person := s.create(t, "Name", 20)
+
+	for name, version := range map[string]int{"exact": 1, "any": models.AnyVersion} {
+		p := s.personBuilder.WithID(100500).WithName("Name").WithVersion(version).Build()
+		_, err := s.repo.Replace(ctx, &p, true)
+		t.Assert().ErrorIs(err, models.ErrPreconditionFailed, name)
+	}
+
+	existing := s.personBuilder.WithID(person.ID).WithName("Other").WithVersion(models.NoVersion).Build()
+	_, err := s.repo.Replace(ctx, &existing, true)
+	t.Assert().ErrorIs(err, models.ErrPreconditionFailed)
+
+	created := s.personBuilder.WithID(100500).WithName("Name").WithVersion(models.NoVersion).Build()
+	ok, err := s.repo.Replace(ctx, &created, true)
+	t.Require().NoError(err)
+	t.Assert().True(ok)
+	t.Assert().Equal(1, created.Version)
+
+	replaced := s.personBuilder.WithID(person.ID).WithName("Other").WithVersion(models.AnyVersion).Build()
+	ok, err = s.repo.Replace(ctx, &replaced, true)
+	t.Require().NoError(err)
+	t.Assert().False(ok)
+	t.Assert().Equal(person.Version+1, replaced.Version)
+}
+
+func (s *PersonRepoSuite) TestPartialUpdate(t provider.T) {
+	ctx := context.Background()
+	person := s.create(t, "Name", 20)
+
+	address := "New address"
+	updated, err := s.repo.Update(ctx, &models.PersonPatch{ID: person.ID, Address: &address})
+	t.Require().NoError(err)
+
+	person.Address = address
+	person.Version++
+	t.Assert().Equal(person, *updated)
+
+	stored, err := s.repo.Get(ctx, person.ID)
+	t.Require().NoError(err)
+	t.Assert().Equal(person, *stored)
+}
+
+func (s *PersonRepoSuite) TestUpdateZeroValues(t provider.T) {
+	ctx := context.Background()
+	person := s.create(t, "Name", 20)
+
+	age, work := 0, ""
+	updated, err := s.repo.Update(ctx, &models.PersonPatch{ID: person.ID, Age: &age, Work: &work})
+	t.Require().NoError(err)
+
+	person.Age = 0
+	person.Work = ""
+	person.Version++
+	t.Assert().Equal(person, *updated)
+
+	stored, err := s.repo.Get(ctx, person.ID)
+	t.Require().NoError(err)
+	t.Assert().Equal(person, *stored)
+}
+
+func (s *PersonRepoSuite) TestReplace(t provider.T) {
+	ctx := context.Background()
+	person := s.create(t, "Name", 20)
+
+	replacement := models.Person{ID: person.ID, Name: "New name"}
+	created, err := s.repo.Replace(ctx, &replacement, false)
+	t.Require().NoError(err)
+	t.Assert().False(created)
+	t.Assert().Equal(models.Person{ID: person.ID, Name: "New name", Version: 2}, replacement)
+
+	stored, err := s.repo.Get(ctx, person.ID)
+	t.Require().NoError(err)
+	t.Assert().Equal(replacement, *stored)
+
+	stale := models.Person{ID: person.ID, Name: "Stale", Version: 1}
+	_, err = s.repo.Replace(ctx, &stale, true)
+	t.Assert().ErrorIs(err, models.ErrPreconditionFailed)
+
+	missing := models.Person{ID: person.ID + 100, Name: "Missing"}
+	_, err = s.repo.Replace(ctx, &missing, false)
+	t.Assert().ErrorIs(err, models.ErrNotFound)
+}
+
+func (s *PersonRepoSuite) TestReplaceUpsert(t provider.T) {
+	ctx := context.Background()
+	existing := s.create(t, "Existing", 20)
+
+	person := models.Person{ID: existing.ID + 100, Name: "Name", Age: 30}
+	created, err := s.repo.Replace(ctx, &person, true)
+	t.Require().NoError(err)
+	t.Assert().True(created)
+	t.Assert().Equal(1, person.Version)
+
+	person.Age = 31
+	created, err = s.repo.Replace(ctx, &person, true)
+	t.Require().NoError(err)
+	t.Assert().False(created)
+	t.Assert().Equal(2, person.Version)
+
+	next := s.create(t, "Next", 40)
+	t.Assert().Greater(next.ID, person.ID)
+}
+
+func (s *PersonRepoSuite) TestDelete(t provider.T) {
+	ctx := context.Background()
+	person := s.create(t, "Name", 20)
+	other := s.create(t, "Other", 30)
+
+	err := s.repo.Delete(ctx, person.ID, 0)
+	t.Require().NoError(err)
+
+	_, err = s.repo.Get(ctx, person.ID)
+	t.Assert().ErrorIs(err, models.ErrNotFound)
+
+	_, err = s.repo.Get(ctx, other.ID)
+	t.Assert().NoError(err)
+}
+
+func (s *PersonRepoSuite) TestTrash(t provider.T) {
+	ctx := context.Background()
+	person := s.create(t, "Name", 20)
+	other := s.create(t, "Other", 30)
+
+	err := s.repo.Delete(ctx, person.ID, 0)
+	t.Require().NoError(err)
+
+	live, err := s.repo.GetAll(ctx, models.PersonQuery{Limit: 10})
+	t.Require().NoError(err)
+	t.Assert().Equal(int64(1), live.Total)
+	t.Assert().Equal([]*models.Person{&other}, live.Persons)
+
+	trash, err := s.repo.GetAll(ctx, models.PersonQuery{Limit: 10, Trashed: true})
+	t.Require().NoError(err)
+	t.Require().Len(trash.Persons, 1)
+	t.Assert().Equal(person.ID, trash.Persons[0].ID)
+	t.Assert().True(trash.Persons[0].DeletedAt.Valid)
+	t.Assert().Equal(person.Version+1, trash.Persons[0].Version, "deleting is a write")
+
+	_, err = s.repo.Replace(ctx, &models.Person{ID: person.ID, Name: "Upsert"}, true)
+	t.Assert().ErrorIs(err, models.ErrConflict)
+
+	restored, err := s.repo.Restore(ctx, person.ID)
+	t.Require().NoError(err)
+	person.Version += 2
+	t.Assert().Equal(person, *restored)
+
+	stored, err := s.repo.Get(ctx, person.ID)
+	t.Require().NoError(err)
+	t.Assert().Equal(person, *stored)
+
+	_, err = s.repo.Restore(ctx, person.ID)
+	t.Assert().ErrorIs(err, models.ErrNotFound)
+}
+
+func (s *PersonRepoSuite) TestPurge(t provider.T) {
+	ctx := context.Background()
+	person := s.create(t, "Name", 20)
+	s.create(t, "Other", 30)
+
+	err := s.repo.Delete(ctx, person.ID, 0)
+	t.Require().NoError(err)
+
+	purged, err := s.repo.Purge(ctx, time.Now().Add(-time.Hour))
+	t.Require().NoError(err)
+	t.Assert().Equal(int64(0), purged)
+
+	purged, err = s.repo.Purge(ctx, time.Now().Add(time.Hour))
+	t.Require().NoError(err)
+	t.Assert().Equal(int64(1), purged)
+
+	_, err = s.repo.Restore(ctx, person.ID)
+	t.Assert().ErrorIs(err, models.ErrNotFound)
+
+	live, err := s.repo.GetAll(ctx, models.PersonQuery{Limit: 10})
+	t.Require().NoError(err)
+	t.Assert().Equal(int64(1), live.Total)
+}
+
+func (s *PersonRepoSuite) TestVersion(t provider.T) {
+	ctx := context.Background()
+	person := s.create(t, "Name", 20)
+	t.Require().Equal(1, person.Version)
+
+	age := 21
+	updated, err := s.repo.Update(ctx, &models.PersonPatch{ID: person.ID, Version: 1, Age: &age})
+	t.Require().NoError(err)
+	t.Assert().Equal(2, updated.Version)
+
+	staleAge := 22
+	_, err = s.repo.Update(ctx, &models.PersonPatch{ID: person.ID, Version: 1, Age: &staleAge})
+	t.Assert().ErrorIs(err, models.ErrPreconditionFailed)
+
+	err = s.repo.Delete(ctx, person.ID, 1)
+	t.Assert().ErrorIs(err, models.ErrPreconditionFailed)
+
+	stored, err := s.repo.Get(ctx, person.ID)
+	t.Require().NoError(err)
+	t.Assert().Equal(21, stored.Age)
+
+	err = s.repo.Delete(ctx, person.ID, 2)
+	t.Assert().NoError(err)
+}
+
+func (s *PersonRepoSuite) TestHistory(t provider.T) {
+	ctx := pkgContext.Manager{}.ContextWithUserID(context.Background(), 7)
+	ctx = pkgContext.Manager{}.ContextWithRequestID(ctx, "req-1")
+
+	person := s.personBuilder.WithID(0).WithName("Name").WithAge(20).Build()
+	err := s.repo.Create(ctx, &person)
+	t.Require().NoError(err)
+
+	address := "New address"
+	_, err = s.repo.Update(ctx, &models.PersonPatch{ID: person.ID, Address: &address})
+	t.Require().NoError(err)
+
+	err = s.repo.Delete(ctx, person.ID, 0)
+	t.Require().NoError(err)
+
+	_, err = s.repo.Purge(ctx, time.Now().Add(time.Hour))
+	t.Require().NoError(err)
+
+	page, err := s.repo.History(ctx, models.AuditQuery{PersonID: person.ID, Limit: 10})
+	t.Require().NoError(err)
+	t.Assert().Equal(int64(4), page.Total)
+	t.Require().Len(page.Entries, 4)
+
+	var ops []string
+	for _, e := range page.Entries {
+		ops = append(ops, e.Operation)
+	}
+	t.Assert().Equal([]string{models.AuditPurge, models.AuditDelete, models.AuditUpdate, models.AuditCreate}, ops)
+
+	update := page.Entries[2]
+	t.Assert().Equal(2, update.Version)
+	t.Require().NotNil(update.ActorID)
+	t.Assert().Equal(7, *update.ActorID)
+	t.Assert().Equal("req-1", update.RequestID)
+	t.Require().Len(update.Changes, 1)
+	t.Assert().JSONEq(`""`, string(update.Changes["address"].Before))
+	t.Assert().JSONEq(`"New address"`, string(update.Changes["address"].After))
+
+	t.Assert().JSONEq(`null`, string(page.Entries[3].Changes["name"].Before))
+	t.Assert().JSONEq(`null`, string(page.Entries[0].Changes["name"].After))
+
+	page, err = s.repo.History(ctx, models.AuditQuery{PersonID: person.ID, Limit: 1, Offset: 2})
+	t.Require().NoError(err)
+	t.Assert().Equal(int64(4), page.Total)
+	t.Require().Len(page.Entries, 1)
+	t.Assert().Equal(models.AuditUpdate, page.Entries[0].Operation)
+}
+
+func (s *PersonRepoSuite) TestSnapshots(t provider.T) {
+	ctx := context.Background()
+	beforeCreate := time.Now()
+	person := s.create(t, "Name", 20)
+	created := time.Now()
+
+	age := 21
+	_, err := s.repo.Update(ctx, &models.PersonPatch{ID: person.ID, Age: &age})
+	t.Require().NoError(err)
+	updated := time.Now()
+
+	err = s.repo.Delete(ctx, person.ID, 0)
+	t.Require().NoError(err)
+
+	_, err = s.repo.GetAsOf(ctx, person.ID, beforeCreate)
+	t.Assert().ErrorIs(err, models.ErrNotFound)
+
+	asOf, err := s.repo.GetAsOf(ctx, person.ID, created)
+	t.Require().NoError(err)
+	t.Assert().Equal(&person, asOf)
+
+	asOf, err = s.repo.GetAsOf(ctx, person.ID, updated)
+	t.Require().NoError(err)
+	t.Assert().Equal(21, asOf.Age)
+	t.Assert().Equal(2, asOf.Version)
+
+	_, err = s.repo.GetAsOf(ctx, person.ID, time.Now())
+	t.Assert().ErrorIs(err, models.ErrNotFound)
+
+	first, err := s.repo.GetVersion(ctx, person.ID, 1)
+	t.Require().NoError(err)
+	t.Assert().Equal(&person, first)
+
+	_, err = s.repo.GetVersion(ctx, person.ID, 3)
+	t.Assert().ErrorIs(err, models.ErrNotFound)
+}
+
+func (s *PersonRepoSuite) TestBatch(t provider.T) {
+	ctx := context.Background()
+	person := s.create(t, "Name", 20)
+
+	age := 21
+	created := &models.Person{Name: "Created", Age: 30}
+	ops := []models.BatchOperation{
+		{Op: models.BatchCreate, Person: created},
+		{Op: models.BatchUpdate, Patch: &models.PersonPatch{ID: person.ID, Age: &age}},
+		{Op: models.BatchDelete, ID: 100500},
+	}
+
+	outcomes, err := s.repo.Batch(ctx, ops, true)
+	t.Require().NoError(err)
+	t.Require().Len(outcomes, 3)
+	t.Assert().ErrorIs(outcomes[2].Err, models.ErrNotFound)
+
+	all, err := s.repo.GetAll(ctx, models.PersonQuery{Limit: 10})
+	t.Require().NoError(err)
+	t.Assert().Equal(int64(1), all.Total)
+	stored, err := s.repo.Get(ctx, person.ID)
+	t.Require().NoError(err)
+	t.Assert().Equal(person, *stored)
+
+	created.ID = 0
+	outcomes, err = s.repo.Batch(ctx, ops, false)
+	t.Require().NoError(err)
+	t.Require().Len(outcomes, 3)
+	t.Assert().NoError(outcomes[0].Err)
+	t.Assert().Greater(outcomes[0].Person.ID, person.ID)
+	t.Assert().Equal(1, outcomes[0].Person.Version)
+	t.Assert().NoError(outcomes[1].Err)
+	t.Assert().Equal(21, outcomes[1].Person.Age)
+	t.Assert().ErrorIs(outcomes[2].Err, models.ErrNotFound)
+
+	stored, err = s.repo.Get(ctx, outcomes[0].Person.ID)
+	t.Require().NoError(err)
+	t.Assert().Equal("Created", stored.Name)
+
+	history, err := s.repo.History(ctx, models.AuditQuery{PersonID: stored.ID, Limit: 10})
+	t.Require().NoError(err)
+	t.Assert().Equal(int64(1), history.Total)
+}
+
+func (s *PersonRepoSuite) TestEach(t provider.T) {
+	ctx := context.Background()
+	alice := s.create(t, "Alice", 30)
+	s.create(t, "Bob", 10)
+	carol := s.create(t, "Carol", 40)
+
+	ageGte := 15
+	var persons []*models.Person
+	err := s.repo.Each(ctx, models.PersonFilter{AgeGte: &ageGte}, func(p *models.Person) error {
+		persons = append(persons, p)
+		return nil
+	})
+	t.Require().NoError(err)
+	t.Assert().Equal([]*models.Person{&alice, &carol}, persons)
+
+	stop := errors.New("stop")
+	calls := 0
+	err = s.repo.Each(ctx, models.PersonFilter{}, func(p *models.Person) error {
+		calls++
+		return stop
+	})
+	t.Assert().ErrorIs(err, stop)
+	t.Assert().Equal(1, calls)
+}
+
+func (s *PersonRepoSuite) TestGetAll(t provider.T) {
+	ctx := context.Background()
+	alice := s.create(t, "Alice", 30)
+	bob := s.create(t, "Bob", 20)
+	carol := s.create(t, "Carol", 40)
+	s.create(t, "Dave", 10)
+
+	ageGte := 15
+	q := models.PersonQuery{
+		Limit:  2,
+		Sort:   []models.SortField{{Field: "age", Desc: true}},
+		Filter: models.PersonFilter{AgeGte: &ageGte},
+	}
+
+	page, err := s.repo.GetAll(ctx, q)
+	t.Require().NoError(err)
+	t.Assert().Equal(int64(3), page.Total)
+	t.Assert().Equal([]*models.Person{&carol, &alice}, page.Persons)
+	t.Assert().True(page.HasNext)
+	t.Assert().False(page.HasPrev)
+
+	q.Cursor = models.NewCursor(page.Persons[1], q.Sort, false)
+	page, err = s.repo.GetAll(ctx, q)
+	t.Require().NoError(err)
+	t.Assert().Equal([]*models.Person{&bob}, page.Persons)
+	t.Assert().False(page.HasNext)
+	t.Assert().True(page.HasPrev)
+
+	q.Cursor = models.NewCursor(page.Persons[0], q.Sort, true)
+	page, err = s.repo.GetAll(ctx, q)
+	t.Require().NoError(err)
+	t.Assert().Equal([]*models.Person{&carol, &alice}, page.Persons)
+	t.Assert().False(page.HasPrev)
+}
+
+func (s *PersonRepoSuite) TestEachPage(t provider.T) {
+	ctx := context.Background()
+	alice := s.create(t, "Alice", 30)
+	bob := s.create(t, "Bob", 20)
+	carol := s.create(t, "Carol", 40)
+
+	q := models.PersonQuery{Limit: 2, Sort: []models.SortField{{Field: "age", Desc: true}}}
+	each := func(q models.PersonQuery) (*models.PersonPage, []*models.Person) {
+		var (
+			page    *models.PersonPage
+			persons []*models.Person
+		)
+		err := s.repo.EachPage(ctx, q, func(p *models.PersonPage) error {
+			page = p
+			return nil
+		}, func(p *models.Person) error {
+			t.Require().NotNil(page, "fn called before start")
+			persons = append(persons, p)
+			return nil
+		})
+		t.Require().NoError(err)
+		return page, persons
+	}
+
+	page, persons := each(q)
+	t.Assert().Equal([]*models.Person{&carol, &alice}, persons)
+	t.Assert().Equal(int64(3), page.Total)
+	t.Assert().Nil(page.Persons)
+	t.Assert().Equal(carol.ID, page.First.ID)
+	t.Assert().Equal(alice.ID, page.Last.ID)
+	t.Assert().True(page.HasNext)
+	t.Assert().False(page.HasPrev)
+
+	q.Cursor = models.NewCursor(page.Last, q.Sort, false)
+	page, persons = each(q)
+	t.Assert().Equal([]*models.Person{&bob}, persons)
+	t.Assert().False(page.HasNext)
+	t.Assert().True(page.HasPrev)
+
+	q.Cursor = models.NewCursor(page.First, q.Sort, true)
+	page, persons = each(q)
+	t.Assert().Equal([]*models.Person{&carol, &alice}, persons)
+	t.Assert().False(page.HasPrev)
+
+	q = models.PersonQuery{Limit: 2, Offset: 5}
+	page, persons = each(q)
+	t.Assert().Empty(persons)
+	t.Assert().Nil(page.First)
+	t.Assert().True(page.HasPrev)
+
+	stop := errors.New("stop")
+	err := s.repo.EachPage(ctx, models.PersonQuery{Limit: 2}, func(*models.PersonPage) error {
+		return stop
+	}, func(*models.Person) error {
+		t.Errorf("fn called after start failed")
+		return nil
+	})
+	t.Assert().ErrorIs(err, stop)
+}
@@ -0,0 +1,505 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Davmie/person_service/internal/person/repository"
+	"github.com/Davmie/person_service/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type memPersonRepo struct {
+	mu     sync.RWMutex
+	people map[int]models.Person
+	trash  map[int]models.Person
+	audit  []models.AuditEntry
+	// versions holds the snapshots in the order they were taken.
+	versions []models.PersonSnapshot
+	lastID   int
+}
+
+func New() repository.PersonRepositoryI {
+	return &memPersonRepo{
+		people: make(map[int]models.Person),
+		trash:  make(map[int]models.Person),
+	}
+}
+
+func (mr *memPersonRepo) Create(ctx context.Context, p *models.Person) error {
+	if err := ctx.Err(); err != nil {
+		return errors.Wrap(err, "memPersonRepo.Create error")
+	}
+
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	mr.create(ctx, p)
+
+	return nil
+}
+
+func (mr *memPersonRepo) create(ctx context.Context, p *models.Person) {
+	mr.lastID++
+	p.ID = mr.lastID
+	p.Version = 1
+	mr.people[p.ID] = *p
+	mr.record(ctx, models.AuditCreate, nil, p)
+}
+
+func (mr *memPersonRepo) Get(ctx context.Context, id int) (*models.Person, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.Wrap(err, "memPersonRepo.Get error")
+	}
+
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	p, ok := mr.people[id]
+	if !ok {
+		return nil, errors.Wrap(models.ErrNotFound, "memPersonRepo.Get error")
+	}
+
+	return &p, nil
+}
+
+func (mr *memPersonRepo) Update(ctx context.Context, patch *models.PersonPatch) (*models.Person, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.Wrap(err, "memPersonRepo.Update error")
+	}
+
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	return mr.update(ctx, patch)
+}
+
+func (mr *memPersonRepo) update(ctx context.Context, patch *models.PersonPatch) (*models.Person, error) {
+	stored, ok := mr.people[patch.ID]
+	if !ok {
+		return nil, errors.Wrap(missing(patch.Version), "memPersonRepo.Update error")
+	}
+	if !models.MatchesVersion(patch.Version, stored.Version) {
+		return nil, errors.Wrap(models.ErrPreconditionFailed, "memPersonRepo.Update error")
+	}
+
+	before := stored
+	patch.Apply(&stored)
+	stored.Version++
+	mr.people[patch.ID] = stored
+	mr.record(ctx, models.AuditUpdate, &before, &stored)
+
+	return &stored, nil
+}
+
+func (mr *memPersonRepo) Delete(ctx context.Context, id, version int) error {
+	if err := ctx.Err(); err != nil {
+		return errors.Wrap(err, "memPersonRepo.Delete error")
+	}
+
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	return mr.remove(ctx, id, version)
+}
+
+func (mr *memPersonRepo) remove(ctx context.Context, id, version int) error {
+	stored, ok := mr.people[id]
+	if !ok {
+		return errors.Wrap(missing(version), "memPersonRepo.Delete error")
+	}
+	if !models.MatchesVersion(version, stored.Version) {
+		return errors.Wrap(models.ErrPreconditionFailed, "memPersonRepo.Delete error")
+	}
+
+	delete(mr.people, id)
+	before := stored
+	stored.Version++
+	stored.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	mr.record(ctx, models.AuditDelete, &before, &stored)
+	mr.trash[id] = stored
+
+	return nil
+}
+
+func (mr *memPersonRepo) Restore(ctx context.Context, id int) (*models.Person, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.Wrap(err, "memPersonRepo.Restore error")
+	}
+
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	stored, ok := mr.trash[id]
+	if !ok {
+		return nil, errors.Wrap(models.ErrNotFound, "memPersonRepo.Restore error")
+	}
+
+	delete(mr.trash, id)
+	before := stored
+	stored.DeletedAt = gorm.DeletedAt{}
+	stored.Version++
+	mr.people[id] = stored
+	mr.record(ctx, models.AuditRestore, &before, &stored)
+
+	return &stored, nil
+}
+
+func (mr *memPersonRepo) Purge(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, errors.Wrap(err, "memPersonRepo.Purge error")
+	}
+
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	var purged int64
+	for id, p := range mr.trash {
+		if p.DeletedAt.Time.Before(before) {
+			delete(mr.trash, id)
+			mr.record(ctx, models.AuditPurge, &p, nil)
+			purged++
+		}
+	}
+
+	return purged, nil
+}
+
+func (mr *memPersonRepo) Replace(ctx context.Context, p *models.Person, upsert bool) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, errors.Wrap(err, "memPersonRepo.Replace error")
+	}
+
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	if _, trashed := mr.trash[p.ID]; trashed && upsert {
+		return false, errors.Wrap(models.ErrConflict, "memPersonRepo.Replace error")
+	}
+
+	stored, ok := mr.people[p.ID]
+	if !ok && (!upsert || models.ExpectsExisting(p.Version)) {
+		return false, errors.Wrap(missing(p.Version), "memPersonRepo.Replace error")
+	}
+	if ok && !models.MatchesVersion(p.Version, stored.Version) {
+		return false, errors.Wrap(models.ErrPreconditionFailed, "memPersonRepo.Replace error")
+	}
+
+	p.Version = stored.Version + 1
+	mr.people[p.ID] = *p
+	mr.lastID = max(mr.lastID, p.ID)
+	if ok {
+		mr.record(ctx, models.AuditReplace, &stored, p)
+	} else {
+		mr.record(ctx, models.AuditCreate, nil, p)
+	}
+
+	return !ok, nil
+}
+
+func (mr *memPersonRepo) History(ctx context.Context, q models.AuditQuery) (*models.AuditPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.Wrap(err, "memPersonRepo.History error")
+	}
+
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	page := &models.AuditPage{}
+	for i := len(mr.audit) - 1; i >= 0; i-- {
+		if mr.audit[i].PersonID != q.PersonID {
+			continue
+		}
+
+		page.Total++
+		if page.Total > int64(q.Offset) && len(page.Entries) < q.Limit {
+			entry := mr.audit[i]
+			page.Entries = append(page.Entries, &entry)
+		}
+	}
+
+	return page, nil
+}
+
+func (mr *memPersonRepo) GetAsOf(ctx context.Context, id int, at time.Time) (*models.Person, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.Wrap(err, "memPersonRepo.GetAsOf error")
+	}
+
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	for i := len(mr.versions) - 1; i >= 0; i-- {
+		s := mr.versions[i]
+		if s.PersonID != id || s.CreatedAt.After(at) {
+			continue
+		}
+		if s.Deleted {
+			break
+		}
+
+		return s.Person(), nil
+	}
+
+	return nil, errors.Wrap(models.ErrNotFound, "memPersonRepo.GetAsOf error")
+}
+
+func (mr *memPersonRepo) GetVersion(ctx context.Context, id, version int) (*models.Person, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.Wrap(err, "memPersonRepo.GetVersion error")
+	}
+
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	for _, s := range mr.versions {
+		if s.PersonID == id && s.Version == version && !s.Deleted {
+			return s.Person(), nil
+		}
+	}
+
+	return nil, errors.Wrap(models.ErrNotFound, "memPersonRepo.GetVersion error")
+}
+
+func (mr *memPersonRepo) Batch(ctx context.Context, ops []models.BatchOperation, atomic bool) ([]models.BatchOutcome, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.Wrap(err, "memPersonRepo.Batch error")
+	}
+
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	rollback := mr.checkpoint()
+	outcomes := make([]models.BatchOutcome, len(ops))
+
+	for i, op := range ops {
+		if op.Op == models.BatchCreate {
+			mr.create(ctx, op.Person)
+			outcomes[i].Person = op.Person
+		}
+	}
+
+	for i, op := range ops {
+		var err error
+		switch op.Op {
+		case models.BatchCreate:
+			continue
+		case models.BatchUpdate:
+			outcomes[i].Person, err = mr.update(ctx, op.Patch)
+		case models.BatchDelete:
+			err = mr.remove(ctx, op.ID, op.Version)
+		default:
+			err = errors.Wrapf(models.ErrValidation, "unknown operation %q", op.Op)
+		}
+
+		if err != nil {
+			outcomes[i].Err = errors.Wrap(err, "memPersonRepo.Batch error")
+			if atomic {
+				rollback()
+				return outcomes, nil
+			}
+		}
+	}
+
+	return outcomes, nil
+}
+
+// checkpoint saves the state and returns a func that restores it; the caller
+// holds the write lock.
+func (mr *memPersonRepo) checkpoint() func() {
+	people, trash := maps.Clone(mr.people), maps.Clone(mr.trash)
+	audit, versions, lastID := len(mr.audit), len(mr.versions), mr.lastID
+
+	return func() {
+		mr.people, mr.trash = people, trash
+		mr.audit, mr.versions = mr.audit[:audit], mr.versions[:versions]
+		mr.lastID = lastID
+	}
+}
+
+// record appends an audit entry and, unless the person was purged, a
+// snapshot; the caller holds the write lock.
+func (mr *memPersonRepo) record(ctx context.Context, op string, before, after *models.Person) {
+	entry := repository.NewAuditEntry(ctx, op, before, after)
+	entry.ID = int64(len(mr.audit) + 1)
+	mr.audit = append(mr.audit, *entry)
+
+	if after != nil {
+		snapshot := models.NewPersonSnapshot(after, op == models.AuditDelete, entry.CreatedAt)
+		snapshot.ID = int64(len(mr.versions) + 1)
+		mr.versions = append(mr.versions, *snapshot)
+	}
+}
+
+func (mr *memPersonRepo) GetAll(ctx context.Context, q models.PersonQuery) (*models.PersonPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.Wrap(err, "memPersonRepo.GetAll error")
+	}
+
+	mr.mu.RLock()
+	people := mr.people
+	if q.Trashed {
+		people = mr.trash
+	}
+	persons := make([]*models.Person, 0, len(people))
+	for _, p := range people {
+		if matchFilter(&p, q.Filter) {
+			p := p
+			persons = append(persons, &p)
+		}
+	}
+	mr.mu.RUnlock()
+
+	order := sortWithID(q.Sort)
+	sort.Slice(persons, func(i, j int) bool {
+		return compare(persons[i], persons[j], order) < 0
+	})
+
+	page := &models.PersonPage{Total: int64(len(persons))}
+
+	switch {
+	case q.Cursor == nil:
+		start := min(q.Offset, len(persons))
+		end := min(start+q.Limit, len(persons))
+		page.Persons = persons[start:end]
+		page.HasNext = end < len(persons)
+		page.HasPrev = q.Offset > 0
+	case q.Cursor.Before:
+		end := sort.Search(len(persons), func(i int) bool {
+			return compare(persons[i], &q.Cursor.Key, order) >= 0
+		})
+		start := max(end-q.Limit, 0)
+		page.Persons = persons[start:end]
+		page.HasNext = true
+		page.HasPrev = start > 0
+	default:
+		start := sort.Search(len(persons), func(i int) bool {
+			return compare(persons[i], &q.Cursor.Key, order) > 0
+		})
+		end := min(start+q.Limit, len(persons))
+		page.Persons = persons[start:end]
+		page.HasNext = end < len(persons)
+		page.HasPrev = true
+	}
+
+	return page, nil
+}
+
+func (mr *memPersonRepo) Each(ctx context.Context, f models.PersonFilter, fn func(p *models.Person) error) error {
+	mr.mu.RLock()
+	persons := make([]*models.Person, 0, len(mr.people))
+	for _, p := range mr.people {
+		if matchFilter(&p, f) {
+			p := p
+			persons = append(persons, &p)
+		}
+	}
+	mr.mu.RUnlock()
+
+	sort.Slice(persons, func(i, j int) bool {
+		return persons[i].ID < persons[j].ID
+	})
+
+	for _, p := range persons {
+		if err := ctx.Err(); err != nil {
+			return errors.Wrap(err, "memPersonRepo.Each error")
+		}
+		if err := fn(p); err != nil {
+			return errors.Wrap(err, "memPersonRepo.Each error")
+		}
+	}
+
+	return nil
+}
+
+func (mr *memPersonRepo) EachPage(ctx context.Context, q models.PersonQuery, start func(page *models.PersonPage) error, fn func(p *models.Person) error) error {
+	page, err := mr.GetAll(ctx, q)
+	if err != nil {
+		return errors.Wrap(err, "memPersonRepo.EachPage error")
+	}
+
+	persons := page.Persons
+	page.First, page.Last = page.Bounds()
+	page.Persons = nil
+	if err := start(page); err != nil {
+		return errors.Wrap(err, "memPersonRepo.EachPage error")
+	}
+
+	for _, p := range persons {
+		if err := ctx.Err(); err != nil {
+			return errors.Wrap(err, "memPersonRepo.EachPage error")
+		}
+		if err := fn(p); err != nil {
+			return errors.Wrap(err, "memPersonRepo.EachPage error")
+		}
+	}
+
+	return nil
+}
+
+// missing is the error of a write expecting version to a missing person.
+func missing(version int) error {
+	if models.ExpectsExisting(version) {
+		return models.ErrPreconditionFailed
+	}
+
+	return models.ErrNotFound
+}
+
+func matchFilter(p *models.Person, f models.PersonFilter) bool {
+	switch {
+	case f.AgeGte != nil && p.Age < *f.AgeGte:
+		return false
+	case f.AgeLte != nil && p.Age > *f.AgeLte:
+		return false
+	case f.Work != nil && p.Work != *f.Work:
+		return false
+	case f.AddressContains != "" && !strings.Contains(strings.ToLower(p.Address), strings.ToLower(f.AddressContains)):
+		return false
+	case f.NamePrefix != "" && !strings.HasPrefix(p.Name, f.NamePrefix):
+		return false
+	}
+
+	return true
+}
+
+func compare(a, b *models.Person, order []models.SortField) int {
+	for _, f := range order {
+		var c int
+		switch f.Field {
+		case "name":
+			c = strings.Compare(a.Name, b.Name)
+		case "age":
+			c = a.Age - b.Age
+		case "address":
+			c = strings.Compare(a.Address, b.Address)
+		case "work":
+			c = strings.Compare(a.Work, b.Work)
+		default:
+			c = a.ID - b.ID
+		}
+		if f.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+func sortWithID(order []models.SortField) []models.SortField {
+	for _, f := range order {
+		if f.Field == "id" {
+			return order
+		}
+	}
+
+	return append(append([]models.SortField{}, order...), models.SortField{Field: "id"})
+}
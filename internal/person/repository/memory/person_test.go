@@ -0,0 +1,18 @@
+package memory
+
+import (
+	"testing"
+
+	personRep "github.com/Davmie/person_service/internal/person/repository"
+	"github.com/Davmie/person_service/internal/person/repository/conformance"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+)
+
+func TestMemPersonRepoConformance(t *testing.T) {
+	suite.RunSuite(t, &conformance.PersonRepoSuite{
+		NewRepo: func(t provider.T) personRep.PersonRepositoryI {
+			return New()
+		},
+	})
+}
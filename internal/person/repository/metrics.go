@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Davmie/person_service/models"
+	"github.com/Davmie/person_service/pkg/metrics"
+)
+
+type metricsPersonRepo struct {
+	next    PersonRepositoryI
+	metrics *metrics.Metrics
+}
+
+// WithMetrics records the latency and result of every call to repo.
+func WithMetrics(repo PersonRepositoryI, m *metrics.Metrics) PersonRepositoryI {
+	return &metricsPersonRepo{next: repo, metrics: m}
+}
+
+func (mr *metricsPersonRepo) Create(ctx context.Context, p *models.Person) error {
+	start := time.Now()
+	err := mr.next.Create(ctx, p)
+	mr.metrics.ObserveQuery("person", "Create", start, err)
+
+	return err
+}
+
+func (mr *metricsPersonRepo) Get(ctx context.Context, id int) (*models.Person, error) {
+	start := time.Now()
+	p, err := mr.next.Get(ctx, id)
+	mr.metrics.ObserveQuery("person", "Get", start, err)
+
+	return p, err
+}
+
+func (mr *metricsPersonRepo) Update(ctx context.Context, patch *models.PersonPatch) (*models.Person, error) {
+	start := time.Now()
+	p, err := mr.next.Update(ctx, patch)
+	mr.metrics.ObserveQuery("person", "Update", start, err)
+
+	return p, err
+}
+
+func (mr *metricsPersonRepo) Replace(ctx context.Context, p *models.Person, upsert bool) (bool, error) {
+	start := time.Now()
+	created, err := mr.next.Replace(ctx, p, upsert)
+	mr.metrics.ObserveQuery("person", "Replace", start, err)
+
+	return created, err
+}
+
+func (mr *metricsPersonRepo) Restore(ctx context.Context, id int) (*models.Person, error) {
+	start := time.Now()
+	p, err := mr.next.Restore(ctx, id)
+	mr.metrics.ObserveQuery("person", "Restore", start, err)
+
+	return p, err
+}
+
+func (mr *metricsPersonRepo) Purge(ctx context.Context, before time.Time) (int64, error) {
+	start := time.Now()
+	purged, err := mr.next.Purge(ctx, before)
+	mr.metrics.ObserveQuery("person", "Purge", start, err)
+
+	return purged, err
+}
+
+func (mr *metricsPersonRepo) History(ctx context.Context, q models.AuditQuery) (*models.AuditPage, error) {
+	start := time.Now()
+	page, err := mr.next.History(ctx, q)
+	mr.metrics.ObserveQuery("person", "History", start, err)
+
+	return page, err
+}
+
+func (mr *metricsPersonRepo) GetAsOf(ctx context.Context, id int, at time.Time) (*models.Person, error) {
+	start := time.Now()
+	p, err := mr.next.GetAsOf(ctx, id, at)
+	mr.metrics.ObserveQuery("person", "GetAsOf", start, err)
+
+	return p, err
+}
+
+func (mr *metricsPersonRepo) GetVersion(ctx context.Context, id, version int) (*models.Person, error) {
+	start := time.Now()
+	p, err := mr.next.GetVersion(ctx, id, version)
+	mr.metrics.ObserveQuery("person", "GetVersion", start, err)
+
+	return p, err
+}
+
+func (mr *metricsPersonRepo) Batch(ctx context.Context, ops []models.BatchOperation, atomic bool) ([]models.BatchOutcome, error) {
+	start := time.Now()
+	outcomes, err := mr.next.Batch(ctx, ops, atomic)
+	mr.metrics.ObserveQuery("person", "Batch", start, err)
+
+	return outcomes, err
+}
+
+func (mr *metricsPersonRepo) Each(ctx context.Context, f models.PersonFilter, fn func(p *models.Person) error) error {
+	start := time.Now()
+	err := mr.next.Each(ctx, f, fn)
+	mr.metrics.ObserveQuery("person", "Each", start, err)
+
+	return err
+}
+
+func (mr *metricsPersonRepo) EachPage(ctx context.Context, q models.PersonQuery, start func(page *models.PersonPage) error, fn func(p *models.Person) error) error {
+	began := time.Now()
+	err := mr.next.EachPage(ctx, q, start, fn)
+	mr.metrics.ObserveQuery("person", "EachPage", began, err)
+
+	return err
+}
+
+func (mr *metricsPersonRepo) Delete(ctx context.Context, id, version int) error {
+	start := time.Now()
+	err := mr.next.Delete(ctx, id, version)
+	mr.metrics.ObserveQuery("person", "Delete", start, err)
+
+	return err
+}
+
+func (mr *metricsPersonRepo) GetAll(ctx context.Context, q models.PersonQuery) (*models.PersonPage, error) {
+	start := time.Now()
+	page, err := mr.next.GetAll(ctx, q)
+	mr.metrics.ObserveQuery("person", "GetAll", start, err)
+
+	return page, err
+}
@@ -3,6 +3,9 @@
 package mocks
 
 import (
+	context "context"
+	time "time"
+
 	models "github.com/Davmie/person_service/models"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -12,13 +15,39 @@ type PersonRepositoryI struct {
 	mock.Mock
 }
 
-// Create provides a mock function with given fields: a
-func (_m *PersonRepositoryI) Create(a *models.Person) error {
-	ret := _m.Called(a)
+// Batch provides a mock function with given fields: ctx, ops, atomic
+func (_m *PersonRepositoryI) Batch(ctx context.Context, ops []models.BatchOperation, atomic bool) ([]models.BatchOutcome, error) {
+	ret := _m.Called(ctx, ops, atomic)
+
+	var r0 []models.BatchOutcome
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []models.BatchOperation, bool) ([]models.BatchOutcome, error)); ok {
+		return rf(ctx, ops, atomic)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []models.BatchOperation, bool) []models.BatchOutcome); ok {
+		r0 = rf(ctx, ops, atomic)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.BatchOutcome)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []models.BatchOperation, bool) error); ok {
+		r1 = rf(ctx, ops, atomic)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Create provides a mock function with given fields: ctx, p
+func (_m *PersonRepositoryI) Create(ctx context.Context, p *models.Person) error {
+	ret := _m.Called(ctx, p)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*models.Person) error); ok {
-		r0 = rf(a)
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Person) error); ok {
+		r0 = rf(ctx, p)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -26,13 +55,13 @@ func (_m *PersonRepositoryI) Create(a *models.Person) error {
 	return r0
 }
 
-// Delete provides a mock function with given fields: id
-func (_m *PersonRepositoryI) Delete(id int) error {
-	ret := _m.Called(id)
+// Delete provides a mock function with given fields: ctx, id, version
+func (_m *PersonRepositoryI) Delete(ctx context.Context, id int, version int) error {
+	ret := _m.Called(ctx, id, version)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(int) error); ok {
-		r0 = rf(id)
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) error); ok {
+		r0 = rf(ctx, id, version)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -40,25 +69,53 @@ func (_m *PersonRepositoryI) Delete(id int) error {
 	return r0
 }
 
-// Get provides a mock function with given fields: id
-func (_m *PersonRepositoryI) Get(id int) (*models.Person, error) {
-	ret := _m.Called(id)
+// Each provides a mock function with given fields: ctx, f, fn
+func (_m *PersonRepositoryI) Each(ctx context.Context, f models.PersonFilter, fn func(*models.Person) error) error {
+	ret := _m.Called(ctx, f, fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.PersonFilter, func(*models.Person) error) error); ok {
+		r0 = rf(ctx, f, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// EachPage provides a mock function with given fields: ctx, q, start, fn
+func (_m *PersonRepositoryI) EachPage(ctx context.Context, q models.PersonQuery, start func(*models.PersonPage) error, fn func(*models.Person) error) error {
+	ret := _m.Called(ctx, q, start, fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.PersonQuery, func(*models.PersonPage) error, func(*models.Person) error) error); ok {
+		r0 = rf(ctx, q, start, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Get provides a mock function with given fields: ctx, id
+func (_m *PersonRepositoryI) Get(ctx context.Context, id int) (*models.Person, error) {
+	ret := _m.Called(ctx, id)
 
 	var r0 *models.Person
 	var r1 error
-	if rf, ok := ret.Get(0).(func(int) (*models.Person, error)); ok {
-		return rf(id)
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*models.Person, error)); ok {
+		return rf(ctx, id)
 	}
-	if rf, ok := ret.Get(0).(func(int) *models.Person); ok {
-		r0 = rf(id)
+	if rf, ok := ret.Get(0).(func(context.Context, int) *models.Person); ok {
+		r0 = rf(ctx, id)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*models.Person)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(int) error); ok {
-		r1 = rf(id)
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, id)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -66,25 +123,25 @@ func (_m *PersonRepositoryI) Get(id int) (*models.Person, error) {
 	return r0, r1
 }
 
-// GetAll provides a mock function with given fields:
-func (_m *PersonRepositoryI) GetAll() ([]*models.Person, error) {
-	ret := _m.Called()
+// GetAll provides a mock function with given fields: ctx, q
+func (_m *PersonRepositoryI) GetAll(ctx context.Context, q models.PersonQuery) (*models.PersonPage, error) {
+	ret := _m.Called(ctx, q)
 
-	var r0 []*models.Person
+	var r0 *models.PersonPage
 	var r1 error
-	if rf, ok := ret.Get(0).(func() ([]*models.Person, error)); ok {
-		return rf()
+	if rf, ok := ret.Get(0).(func(context.Context, models.PersonQuery) (*models.PersonPage, error)); ok {
+		return rf(ctx, q)
 	}
-	if rf, ok := ret.Get(0).(func() []*models.Person); ok {
-		r0 = rf()
+	if rf, ok := ret.Get(0).(func(context.Context, models.PersonQuery) *models.PersonPage); ok {
+		r0 = rf(ctx, q)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]*models.Person)
+			r0 = ret.Get(0).(*models.PersonPage)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func() error); ok {
-		r1 = rf()
+	if rf, ok := ret.Get(1).(func(context.Context, models.PersonQuery) error); ok {
+		r1 = rf(ctx, q)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -92,18 +149,182 @@ func (_m *PersonRepositoryI) GetAll() ([]*models.Person, error) {
 	return r0, r1
 }
 
-// Update provides a mock function with given fields: a
-func (_m *PersonRepositoryI) Update(a *models.Person) error {
-	ret := _m.Called(a)
+// GetAsOf provides a mock function with given fields: ctx, id, at
+func (_m *PersonRepositoryI) GetAsOf(ctx context.Context, id int, at time.Time) (*models.Person, error) {
+	ret := _m.Called(ctx, id, at)
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(*models.Person) error); ok {
-		r0 = rf(a)
+	var r0 *models.Person
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Time) (*models.Person, error)); ok {
+		return rf(ctx, id, at)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Time) *models.Person); ok {
+		r0 = rf(ctx, id, at)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Person)
+		}
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, int, time.Time) error); ok {
+		r1 = rf(ctx, id, at)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVersion provides a mock function with given fields: ctx, id, version
+func (_m *PersonRepositoryI) GetVersion(ctx context.Context, id int, version int) (*models.Person, error) {
+	ret := _m.Called(ctx, id, version)
+
+	var r0 *models.Person
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) (*models.Person, error)); ok {
+		return rf(ctx, id, version)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) *models.Person); ok {
+		r0 = rf(ctx, id, version)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Person)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int) error); ok {
+		r1 = rf(ctx, id, version)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// History provides a mock function with given fields: ctx, q
+func (_m *PersonRepositoryI) History(ctx context.Context, q models.AuditQuery) (*models.AuditPage, error) {
+	ret := _m.Called(ctx, q)
+
+	var r0 *models.AuditPage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.AuditQuery) (*models.AuditPage, error)); ok {
+		return rf(ctx, q)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.AuditQuery) *models.AuditPage); ok {
+		r0 = rf(ctx, q)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.AuditPage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.AuditQuery) error); ok {
+		r1 = rf(ctx, q)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Purge provides a mock function with given fields: ctx, before
+func (_m *PersonRepositoryI) Purge(ctx context.Context, before time.Time) (int64, error) {
+	ret := _m.Called(ctx, before)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) (int64, error)); ok {
+		return rf(ctx, before)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int64); ok {
+		r0 = rf(ctx, before)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, before)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Replace provides a mock function with given fields: ctx, p, upsert
+func (_m *PersonRepositoryI) Replace(ctx context.Context, p *models.Person, upsert bool) (bool, error) {
+	ret := _m.Called(ctx, p, upsert)
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Person, bool) (bool, error)); ok {
+		return rf(ctx, p, upsert)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Person, bool) bool); ok {
+		r0 = rf(ctx, p, upsert)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.Person, bool) error); ok {
+		r1 = rf(ctx, p, upsert)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Restore provides a mock function with given fields: ctx, id
+func (_m *PersonRepositoryI) Restore(ctx context.Context, id int) (*models.Person, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 *models.Person
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*models.Person, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) *models.Person); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Person)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Update provides a mock function with given fields: ctx, patch
+func (_m *PersonRepositoryI) Update(ctx context.Context, patch *models.PersonPatch) (*models.Person, error) {
+	ret := _m.Called(ctx, patch)
+
+	var r0 *models.Person
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.PersonPatch) (*models.Person, error)); ok {
+		return rf(ctx, patch)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.PersonPatch) *models.Person); ok {
+		r0 = rf(ctx, patch)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Person)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.PersonPatch) error); ok {
+		r1 = rf(ctx, patch)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // NewPersonRepositoryI creates a new instance of PersonRepositoryI. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
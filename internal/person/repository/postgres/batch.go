@@ -0,0 +1,185 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/Davmie/person_service/internal/person/repository"
+	"github.com/Davmie/person_service/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// batchInsertSize is the number of rows per INSERT statement of a batch.
+const batchInsertSize = 100
+
+// batchSavepoint marks the state before each operation of a best-effort
+// batch, so that a failed one can be undone alone.
+const batchSavepoint = "batch_operation"
+
+// errBatchFailed rolls back the transaction of an atomic batch.
+var errBatchFailed = errors.New("batch failed")
+
+func (pr *pgPersonRepo) Batch(ctx context.Context, ops []models.BatchOperation, atomic bool) ([]models.BatchOutcome, error) {
+	outcomes := make([]models.BatchOutcome, len(ops))
+
+	err := pr.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		run := &batchRun{pr: pr, ctx: ctx, tx: tx, atomic: atomic, outcomes: outcomes}
+
+		var creates []int
+		for i, op := range ops {
+			if op.Op == models.BatchCreate {
+				creates = append(creates, i)
+			}
+		}
+		if err := run.create(ops, creates); err != nil {
+			return err
+		}
+
+		for i, op := range ops {
+			var err error
+			switch op.Op {
+			case models.BatchCreate:
+				continue
+			case models.BatchUpdate:
+				err = run.step(i, func() (*models.Person, error) {
+					return update(ctx, tx, op.Patch)
+				})
+			case models.BatchDelete:
+				err = run.step(i, func() (*models.Person, error) {
+					return nil, remove(ctx, tx, op.ID, op.Version)
+				})
+			default:
+				err = run.step(i, func() (*models.Person, error) {
+					return nil, errors.Wrapf(models.ErrValidation, "unknown operation %q", op.Op)
+				})
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil && !errors.Is(err, errBatchFailed) {
+		return nil, errors.Wrap(pr.queryError(ctx, err), "pgPersonRepo.Batch error")
+	}
+
+	return outcomes, nil
+}
+
+// batchRun applies the operations of a batch in its transaction.
+type batchRun struct {
+	pr       *pgPersonRepo
+	ctx      context.Context
+	tx       *gorm.DB
+	atomic   bool
+	outcomes []models.BatchOutcome
+}
+
+// step runs the operation at i and records its outcome. It returns an error
+// only when the transaction has to end.
+func (b *batchRun) step(i int, apply func() (*models.Person, error)) error {
+	return b.guard(func() error {
+		p, err := apply()
+		if err != nil {
+			b.outcomes[i].Err = b.pr.queryError(b.ctx, err)
+			return err
+		}
+
+		b.outcomes[i].Person = p
+		return nil
+	})
+}
+
+// guard runs apply. When it fails, an atomic batch is rolled back and a
+// best-effort one returns to the state before it.
+func (b *batchRun) guard(apply func() error) error {
+	if !b.atomic {
+		if err := b.tx.SavePoint(batchSavepoint).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := apply(); err != nil {
+		if b.atomic {
+			return errBatchFailed
+		}
+		return b.tx.RollbackTo(batchSavepoint).Error
+	}
+
+	return nil
+}
+
+// create inserts the persons of the create operations at indexes together.
+// When that fails in a best-effort batch they are inserted one by one, so
+// that only the failing ones are skipped.
+func (b *batchRun) create(ops []models.BatchOperation, indexes []int) error {
+	if len(indexes) == 0 {
+		return nil
+	}
+
+	persons := make([]*models.Person, 0, len(indexes))
+	for _, i := range indexes {
+		persons = append(persons, ops[i].Person)
+	}
+
+	failed := false
+	err := b.guard(func() error {
+		err := insert(b.ctx, b.tx, persons)
+		if err != nil {
+			failed = true
+			if b.atomic {
+				// The failing row is unknown, so every create shares the error.
+				err = b.pr.queryError(b.ctx, err)
+				for _, i := range indexes {
+					b.outcomes[i].Err = err
+				}
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if !failed {
+		for n, i := range indexes {
+			b.outcomes[i].Person = persons[n]
+		}
+		return nil
+	}
+
+	for n, i := range indexes {
+		p := persons[n]
+		p.ID = 0
+		err = b.step(i, func() (*models.Person, error) {
+			return p, insert(b.ctx, b.tx, []*models.Person{p})
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// insert creates persons in as few statements as possible and records them.
+func insert(ctx context.Context, tx *gorm.DB, persons []*models.Person) error {
+	if err := tx.CreateInBatches(persons, batchInsertSize).Error; err != nil {
+		return err
+	}
+
+	entries := make([]*models.AuditEntry, 0, len(persons))
+	snapshots := make([]*models.PersonSnapshot, 0, len(persons))
+	for _, p := range persons {
+		entry := repository.NewAuditEntry(ctx, models.AuditCreate, nil, p)
+		entries = append(entries, entry)
+		snapshots = append(snapshots, models.NewPersonSnapshot(p, false, entry.CreatedAt))
+	}
+
+	if err := tx.CreateInBatches(entries, batchInsertSize).Error; err != nil {
+		return err
+	}
+
+	return tx.CreateInBatches(snapshots, batchInsertSize).Error
+}
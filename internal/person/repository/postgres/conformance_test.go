@@ -0,0 +1,38 @@
+package postgres
+
+import (
+	"os"
+	"testing"
+
+	personRep "github.com/Davmie/person_service/internal/person/repository"
+	"github.com/Davmie/person_service/internal/person/repository/conformance"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// TestPgPersonRepoConformance runs against a real database and is skipped
+// unless PERSON_SERVICE_TEST_POSTGRES_DSN is set. The people table is
+// truncated before each test.
+func TestPgPersonRepoConformance(t *testing.T) {
+	dsn := os.Getenv("PERSON_SERVICE_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("PERSON_SERVICE_TEST_POSTGRES_DSN is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	suite.RunSuite(t, &conformance.PersonRepoSuite{
+		NewRepo: func(t provider.T) personRep.PersonRepositoryI {
+			err := db.Exec("TRUNCATE people, person_audit, person_versions RESTART IDENTITY").Error
+			t.Require().NoError(err)
+
+			return New(zap.NewNop().Sugar(), db)
+		},
+	})
+}
@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"net"
+	"strings"
+
+	"github.com/Davmie/person_service/models"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// translateError maps driver and gorm errors to the domain errors from models,
+// keeping the original error in the chain.
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &domainError{kind: models.ErrNotFound, err: err}
+	}
+
+	if kind := kindByCode(sqlState(err)); kind != nil {
+		return &domainError{kind: kind, err: err}
+	}
+
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) {
+		return &domainError{kind: models.ErrUnavailable, err: err}
+	}
+
+	return err
+}
+
+func sqlState(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return string(pqErr.Code)
+	}
+
+	return ""
+}
+
+func kindByCode(code string) error {
+	switch {
+	case code == "":
+		return nil
+	case code == "23505", code == "23503", code == "40001":
+		return models.ErrConflict
+	case code == "23502", code == "23514", code == "22001", code == "22003", code == "22P02":
+		return models.ErrValidation
+	case strings.HasPrefix(code, "08"), strings.HasPrefix(code, "53"), strings.HasPrefix(code, "57P"):
+		return models.ErrUnavailable
+	}
+
+	return nil
+}
+
+type domainError struct {
+	kind error
+	err  error
+}
+
+func (e *domainError) Error() string {
+	return e.kind.Error() + ": " + e.err.Error()
+}
+
+func (e *domainError) Is(target error) bool {
+	return target == e.kind
+}
+
+func (e *domainError) Unwrap() error {
+	return e.err
+}
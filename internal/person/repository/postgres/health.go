@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/Davmie/person_service/pkg/health"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// HealthCheck pings the database and checks that the people table has been
+// migrated.
+func HealthCheck(db *gorm.DB) health.Checker {
+	return health.CheckerFunc(func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return errors.Wrap(err, "can`t get database handle")
+		}
+
+		if err = sqlDB.PingContext(ctx); err != nil {
+			return errors.Wrap(err, "can`t ping database")
+		}
+
+		var exists bool
+		err = sqlDB.QueryRowContext(ctx, `SELECT to_regclass('people') IS NOT NULL`).Scan(&exists)
+		if err != nil {
+			return errors.Wrap(err, "can`t check people table")
+		}
+		if !exists {
+			return errors.New("people table does not exist")
+		}
+
+		return nil
+	})
+}
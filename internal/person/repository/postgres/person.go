@@ -1,6 +1,10 @@
 package postgres
 
 import (
+	"context"
+	"strings"
+	"time"
+
 	"github.com/Davmie/person_service/internal/person/repository"
 	"github.com/Davmie/person_service/models"
 	"github.com/Davmie/person_service/pkg/logger"
@@ -9,6 +13,9 @@ import (
 	"gorm.io/gorm/clause"
 )
 
+// eachBatchSize is the number of rows Each reads at a time.
+const eachBatchSize = 500
+
 type pgPersonRepo struct {
 	Logger logger.Logger
 	DB     *gorm.DB
@@ -21,55 +28,536 @@ func New(logger logger.Logger, db *gorm.DB) repository.PersonRepositoryI {
 	}
 }
 
-func (pr *pgPersonRepo) Create(p *models.Person) error {
-	tx := pr.DB.Create(p)
+func (pr *pgPersonRepo) Create(ctx context.Context, p *models.Person) error {
+	err := pr.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(p).Error; err != nil {
+			return err
+		}
 
-	if tx.Error != nil {
-		return errors.Wrap(tx.Error, "pgPersonRepo.Create error while inserting in repo")
+		return record(ctx, tx, models.AuditCreate, nil, p)
+	})
+
+	if err != nil {
+		return errors.Wrap(pr.queryError(ctx, err), "pgPersonRepo.Create error while inserting in repo")
 	}
 
 	return nil
 }
 
-func (pr *pgPersonRepo) Get(id int) (*models.Person, error) {
+func (pr *pgPersonRepo) Get(ctx context.Context, id int) (*models.Person, error) {
 	var p models.Person
-	tx := pr.DB.Where("id = ?", id).Take(&p)
+	tx := pr.DB.WithContext(ctx).Where("id = ?", id).Take(&p)
 
 	if tx.Error != nil {
-		return nil, errors.Wrap(tx.Error, "pgPersonRepo.Get error")
+		return nil, errors.Wrap(pr.queryError(ctx, tx.Error), "pgPersonRepo.Get error")
 	}
 
 	return &p, nil
 }
 
-func (pr *pgPersonRepo) Update(p *models.Person) error {
-	tx := pr.DB.Clauses(clause.Returning{}).Omit("id").Updates(p)
+func (pr *pgPersonRepo) Update(ctx context.Context, patch *models.PersonPatch) (*models.Person, error) {
+	var p *models.Person
+	err := pr.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) (err error) {
+		p, err = update(ctx, tx, patch)
+		return err
+	})
 
-	if tx.Error != nil {
-		return errors.Wrap(tx.Error, "pgPersonRepo.Update error while inserting in repo")
+	if err != nil {
+		return nil, errors.Wrap(pr.queryError(ctx, err), "pgPersonRepo.Update error while inserting in repo")
+	}
+
+	return p, nil
+}
+
+func (pr *pgPersonRepo) Delete(ctx context.Context, id, version int) error {
+	err := pr.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return remove(ctx, tx, id, version)
+	})
+
+	if err != nil {
+		return errors.Wrap(pr.queryError(ctx, err), "pgPersonRepo.Delete error")
 	}
 
 	return nil
 }
 
-func (pr *pgPersonRepo) Delete(id int) error {
-	tx := pr.DB.Delete(&models.Person{}, id)
+func update(ctx context.Context, tx *gorm.DB, patch *models.PersonPatch) (*models.Person, error) {
+	before, err := lockPerson(tx, patch.ID, patch.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	return write(ctx, tx, models.AuditUpdate, before, updateColumns(patch))
+}
+
+// remove moves the person to the trash, which is a write like any other and
+// bumps the version.
+func remove(ctx context.Context, tx *gorm.DB, id, version int) error {
+	before, err := lockPerson(tx, id, version)
+	if err != nil {
+		return err
+	}
+
+	_, err = write(ctx, tx, models.AuditDelete, before, map[string]interface{}{
+		"deleted_at": tx.NowFunc(),
+		"version":    gorm.Expr("version + 1"),
+	})
+	return err
+}
+
+func (pr *pgPersonRepo) Replace(ctx context.Context, p *models.Person, upsert bool) (bool, error) {
+	created := false
+	err := pr.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		lock := tx
+		if upsert {
+			lock = tx.Unscoped()
+		}
+
+		before, err := lockPerson(lock, p.ID, p.Version)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound) && upsert:
+			created = true
+			p.Version = 0
+			return createAt(ctx, tx, p)
+		case err != nil:
+			return err
+		case before.DeletedAt.Valid:
+			// A trashed person has to be restored, not overwritten.
+			return models.ErrConflict
+		}
+
+		stored, err := write(ctx, tx, models.AuditReplace, before, updateColumns(p.Patch()))
+		if err != nil {
+			return err
+		}
+
+		*p = *stored
+		return nil
+	})
+
+	if err != nil {
+		return false, errors.Wrap(pr.queryError(ctx, err), "pgPersonRepo.Replace error")
+	}
+
+	return created, nil
+}
+
+// createAt inserts p at the ID the client picked.
+func createAt(ctx context.Context, tx *gorm.DB, p *models.Person) error {
+	if err := tx.Create(p).Error; err != nil {
+		return err
+	}
+
+	// Keep generated IDs clear of the one the client picked.
+	err := tx.Exec(`SELECT setval('people_id_seq', GREATEST(last_value, ?)) FROM people_id_seq`, p.ID).Error
+	if err != nil {
+		return err
+	}
+
+	return record(ctx, tx, models.AuditCreate, nil, p)
+}
+
+func (pr *pgPersonRepo) Restore(ctx context.Context, id int) (*models.Person, error) {
+	var p *models.Person
+	err := pr.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		before, err := lockPerson(tx.Unscoped(), id, 0)
+		if err != nil {
+			return err
+		}
+		if !before.DeletedAt.Valid {
+			return models.ErrNotFound
+		}
+
+		p, err = write(ctx, tx, models.AuditRestore, before, map[string]interface{}{
+			"deleted_at": nil,
+			"version":    gorm.Expr("version + 1"),
+		})
+		return err
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(pr.queryError(ctx, err), "pgPersonRepo.Restore error")
+	}
+
+	return p, nil
+}
+
+func (pr *pgPersonRepo) Purge(ctx context.Context, before time.Time) (int64, error) {
+	var purged []models.Person
+	err := pr.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Clauses(clause.Returning{}).Where("deleted_at < ?", before).Delete(&purged).Error
+		if err != nil || len(purged) == 0 {
+			return err
+		}
+
+		entries := make([]*models.AuditEntry, 0, len(purged))
+		for i := range purged {
+			entries = append(entries, repository.NewAuditEntry(ctx, models.AuditPurge, &purged[i], nil))
+		}
+
+		return tx.Create(entries).Error
+	})
+
+	if err != nil {
+		return 0, errors.Wrap(pr.queryError(ctx, err), "pgPersonRepo.Purge error")
+	}
+
+	return int64(len(purged)), nil
+}
+
+func (pr *pgPersonRepo) History(ctx context.Context, q models.AuditQuery) (*models.AuditPage, error) {
+	page := &models.AuditPage{}
 
+	tx := pr.DB.WithContext(ctx).Model(&models.AuditEntry{}).Where("person_id = ?", q.PersonID).Count(&page.Total)
 	if tx.Error != nil {
-		return errors.Wrap(tx.Error, "pgPersonRepo.Delete error")
+		return nil, errors.Wrap(pr.queryError(ctx, tx.Error), "pgPersonRepo.History error while counting")
 	}
 
-	return nil
+	tx = pr.DB.WithContext(ctx).Where("person_id = ?", q.PersonID).
+		Order("id DESC").Limit(q.Limit).Offset(q.Offset).
+		Find(&page.Entries)
+	if tx.Error != nil {
+		return nil, errors.Wrap(pr.queryError(ctx, tx.Error), "pgPersonRepo.History error")
+	}
+
+	return page, nil
+}
+
+func (pr *pgPersonRepo) GetAsOf(ctx context.Context, id int, at time.Time) (*models.Person, error) {
+	var snapshot models.PersonSnapshot
+	tx := pr.DB.WithContext(ctx).Where("person_id = ? AND created_at <= ?", id, at).Order("id DESC").Take(&snapshot)
+
+	if tx.Error != nil {
+		return nil, errors.Wrap(pr.queryError(ctx, tx.Error), "pgPersonRepo.GetAsOf error")
+	}
+	if snapshot.Deleted {
+		return nil, errors.Wrap(models.ErrNotFound, "pgPersonRepo.GetAsOf error")
+	}
+
+	return snapshot.Person(), nil
+}
+
+func (pr *pgPersonRepo) GetVersion(ctx context.Context, id, version int) (*models.Person, error) {
+	var snapshot models.PersonSnapshot
+	tx := pr.DB.WithContext(ctx).Where("person_id = ? AND version = ? AND NOT deleted", id, version).Take(&snapshot)
+
+	if tx.Error != nil {
+		return nil, errors.Wrap(pr.queryError(ctx, tx.Error), "pgPersonRepo.GetVersion error")
+	}
+
+	return snapshot.Person(), nil
+}
+
+// lockPerson reads the person for the rest of the transaction and checks
+// that it still has the expected version, if one is given.
+func lockPerson(tx *gorm.DB, id, version int) (*models.Person, error) {
+	var p models.Person
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).Take(&p).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) && models.ExpectsExisting(version) {
+		return nil, models.ErrPreconditionFailed
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !models.MatchesVersion(version, p.Version) {
+		return nil, models.ErrPreconditionFailed
+	}
+
+	return &p, nil
+}
+
+// write updates the locked person with columns and records the change.
+func write(ctx context.Context, tx *gorm.DB, op string, before *models.Person, columns map[string]interface{}) (*models.Person, error) {
+	after := models.Person{ID: before.ID}
+	err := tx.Unscoped().Model(&after).Clauses(clause.Returning{}).Updates(columns).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if err = record(ctx, tx, op, before, &after); err != nil {
+		return nil, err
+	}
+
+	return &after, nil
+}
+
+// record writes the audit entry of a change and the snapshot of the person
+// it left behind. A deleted person is snapshotted as it was when trashed.
+func record(ctx context.Context, tx *gorm.DB, op string, before, after *models.Person) error {
+	entry := repository.NewAuditEntry(ctx, op, before, after)
+	if err := tx.Create(entry).Error; err != nil {
+		return err
+	}
+
+	return tx.Create(models.NewPersonSnapshot(after, op == models.AuditDelete, entry.CreatedAt)).Error
+}
+
+// updateColumns lists the set fields of patch, zero values included, and
+// bumps the version.
+func updateColumns(patch *models.PersonPatch) map[string]interface{} {
+	columns := map[string]interface{}{
+		"version": gorm.Expr("version + 1"),
+	}
+	if patch.Name != nil {
+		columns["name"] = *patch.Name
+	}
+	if patch.Age != nil {
+		columns["age"] = *patch.Age
+	}
+	if patch.Address != nil {
+		columns["address"] = *patch.Address
+	}
+	if patch.Work != nil {
+		columns["work"] = *patch.Work
+	}
+
+	return columns
 }
 
-func (pr *pgPersonRepo) GetAll() ([]*models.Person, error) {
+func (pr *pgPersonRepo) GetAll(ctx context.Context, q models.PersonQuery) (*models.PersonPage, error) {
+	page, db, err := pr.pageQuery(ctx, q)
+	if err != nil {
+		return nil, errors.Wrap(err, "pgPersonRepo.GetAll error")
+	}
+
 	var persons []*models.Person
+	tx := db.Limit(q.Limit + 1).Find(&persons)
+	if tx.Error != nil {
+		return nil, errors.Wrap(pr.queryError(ctx, tx.Error), "pgPersonRepo.GetAll error")
+	}
+
+	page.Persons = fillPage(page, q, persons)
+	return page, nil
+}
+
+// EachPage reads only the sort keys of the page up front, which the links
+// need before anything is written, and then streams the rows they name.
+func (pr *pgPersonRepo) EachPage(ctx context.Context, q models.PersonQuery, start func(page *models.PersonPage) error, fn func(p *models.Person) error) error {
+	page, db, err := pr.pageQuery(ctx, q)
+	if err != nil {
+		return errors.Wrap(err, "pgPersonRepo.EachPage error")
+	}
+
+	sort := sortWithID(q.Sort)
+	columns := make([]string, 0, len(sort))
+	for _, f := range sort {
+		columns = append(columns, f.Field)
+	}
+
+	var keys []*models.Person
+	tx := db.Select(columns).Limit(q.Limit + 1).Find(&keys)
+	if tx.Error != nil {
+		return errors.Wrap(pr.queryError(ctx, tx.Error), "pgPersonRepo.EachPage error while reading keys")
+	}
+
+	keys = fillPage(page, q, keys)
+	if len(keys) == 0 {
+		return errors.Wrap(start(page), "pgPersonRepo.EachPage error")
+	}
+
+	page.First, page.Last = keys[0], keys[len(keys)-1]
+	if err := start(page); err != nil {
+		return errors.Wrap(err, "pgPersonRepo.EachPage error")
+	}
+
+	ids := make([]int, 0, len(keys))
+	for _, k := range keys {
+		ids = append(ids, k.ID)
+	}
+
+	db = pr.DB.WithContext(ctx).Model(&models.Person{}).Scopes(trashFilter(q.Trashed)).Where("id IN ?", ids)
+	for _, f := range sort {
+		db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: f.Field}, Desc: f.Desc})
+	}
+	rows, err := db.Rows()
+	if err != nil {
+		return errors.Wrap(pr.queryError(ctx, err), "pgPersonRepo.EachPage error")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.Person
+		if err := pr.DB.ScanRows(rows, &p); err != nil {
+			return errors.Wrap(pr.queryError(ctx, err), "pgPersonRepo.EachPage error")
+		}
+		if err := fn(&p); err != nil {
+			return errors.Wrap(err, "pgPersonRepo.EachPage error")
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return errors.Wrap(pr.queryError(ctx, err), "pgPersonRepo.EachPage error")
+	}
+
+	return nil
+}
+
+// pageQuery counts the matches of q into a new page and returns the query
+// for its rows, ordered and positioned but not yet limited.
+func (pr *pgPersonRepo) pageQuery(ctx context.Context, q models.PersonQuery) (*models.PersonPage, *gorm.DB, error) {
+	page := &models.PersonPage{}
+
+	tx := pr.DB.WithContext(ctx).Model(&models.Person{}).Scopes(trashFilter(q.Trashed), personFilter(q.Filter)).Count(&page.Total)
+	if tx.Error != nil {
+		return nil, nil, errors.Wrap(pr.queryError(ctx, tx.Error), "error while counting")
+	}
+
+	sort := sortWithID(q.Sort)
+	before := q.Cursor != nil && q.Cursor.Before
+
+	db := pr.DB.WithContext(ctx).Model(&models.Person{}).Scopes(trashFilter(q.Trashed), personFilter(q.Filter))
+	if q.Cursor != nil {
+		db = db.Scopes(personKeyset(q.Cursor, sort))
+	} else if q.Offset > 0 {
+		db = db.Offset(q.Offset)
+	}
+	for _, f := range sort {
+		db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: f.Field}, Desc: f.Desc != before})
+	}
+
+	return page, db, nil
+}
+
+// fillPage sets the paging flags from the rows read with a limit one past
+// the page, and returns those of the page in display order.
+func fillPage(page *models.PersonPage, q models.PersonQuery, persons []*models.Person) []*models.Person {
+	more := len(persons) > q.Limit
+	if more {
+		persons = persons[:q.Limit]
+	}
+
+	switch {
+	case q.Cursor == nil:
+		page.HasNext = more
+		page.HasPrev = q.Offset > 0
+	case q.Cursor.Before:
+		for i, j := 0, len(persons)-1; i < j; i, j = i+1, j-1 {
+			persons[i], persons[j] = persons[j], persons[i]
+		}
+		page.HasNext = true
+		page.HasPrev = more
+	default:
+		page.HasNext = more
+		page.HasPrev = true
+	}
+
+	return persons
+}
 
-	tx := pr.DB.Find(&persons)
+func (pr *pgPersonRepo) Each(ctx context.Context, f models.PersonFilter, fn func(p *models.Person) error) error {
+	var (
+		persons []*models.Person
+		fnErr   error
+	)
+	tx := pr.DB.WithContext(ctx).Scopes(personFilter(f)).FindInBatches(&persons, eachBatchSize, func(*gorm.DB, int) error {
+		for _, p := range persons {
+			if fnErr = fn(p); fnErr != nil {
+				return fnErr
+			}
+		}
+		return nil
+	})
 
+	if fnErr != nil {
+		return errors.Wrap(fnErr, "pgPersonRepo.Each error")
+	}
 	if tx.Error != nil {
-		return nil, errors.Wrap(tx.Error, "pgPersonRepo.GetAll error")
+		return errors.Wrap(pr.queryError(ctx, tx.Error), "pgPersonRepo.Each error")
+	}
+
+	return nil
+}
+
+// trashFilter selects only the soft-deleted rows when trashed is set; gorm
+// skips them otherwise.
+func trashFilter(trashed bool) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if !trashed {
+			return db
+		}
+		return db.Unscoped().Where("deleted_at IS NOT NULL")
+	}
+}
+
+func personFilter(f models.PersonFilter) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if f.AgeGte != nil {
+			db = db.Where("age >= ?", *f.AgeGte)
+		}
+		if f.AgeLte != nil {
+			db = db.Where("age <= ?", *f.AgeLte)
+		}
+		if f.Work != nil {
+			db = db.Where("work = ?", *f.Work)
+		}
+		if f.AddressContains != "" {
+			db = db.Where("address ILIKE ?", "%"+escapeLike(f.AddressContains)+"%")
+		}
+		if f.NamePrefix != "" {
+			db = db.Where("name LIKE ?", escapeLike(f.NamePrefix)+"%")
+		}
+		return db
+	}
+}
+
+// personKeyset selects rows strictly after (or before) the cursor row in the
+// given order: (a > x) OR (a = x AND b > y) OR ...
+func personKeyset(c *models.Cursor, sort []models.SortField) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		var (
+			ors  []string
+			args []interface{}
+		)
+		for i, f := range sort {
+			var ands []string
+			for _, prev := range sort[:i] {
+				ands = append(ands, prev.Field+" = ?")
+				args = append(args, c.Value(prev.Field))
+			}
+
+			op := ">"
+			if f.Desc != c.Before {
+				op = "<"
+			}
+			ands = append(ands, f.Field+" "+op+" ?")
+			args = append(args, c.Value(f.Field))
+
+			ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+		}
+
+		return db.Where("("+strings.Join(ors, " OR ")+")", args...)
+	}
+}
+
+func sortWithID(sort []models.SortField) []models.SortField {
+	for _, f := range sort {
+		if f.Field == "id" {
+			return sort
+		}
+	}
+
+	return append(append([]models.SortField{}, sort...), models.SortField{Field: "id"})
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// queryError translates err and logs unexpected failures with the logger of
+// the request.
+func (pr *pgPersonRepo) queryError(ctx context.Context, err error) error {
+	err = translateError(err)
+
+	for _, expected := range []error{models.ErrNotFound, models.ErrConflict, models.ErrValidation, models.ErrPreconditionFailed} {
+		if errors.Is(err, expected) {
+			return err
+		}
+	}
+
+	if l := logger.FromContext(ctx, pr.Logger); l != nil {
+		l.Errorw("query failed",
+			"err:", err.Error())
 	}
 
-	return persons, nil
+	return err
 }
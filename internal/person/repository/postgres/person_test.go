@@ -1,19 +1,23 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"github.com/DATA-DOG/go-sqlmock"
 	personRep "github.com/Davmie/person_service/internal/person/repository"
 	"github.com/Davmie/person_service/internal/testBuilders"
 	"github.com/Davmie/person_service/models"
 	"github.com/Davmie/person_service/pkg/logger"
 	"github.com/bxcodec/faker"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/ozontech/allure-go/pkg/framework/provider"
 	"github.com/ozontech/allure-go/pkg/framework/suite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"regexp"
 	"testing"
+	"time"
 )
 
 type PersonRepoTestSuite struct {
@@ -62,6 +66,30 @@ func (s *PersonRepoTestSuite) AfterEach(t provider.T) {
 	s.db.Close()
 }
 
+// expectRecord expects the audit entry and the snapshot written with a
+// change.
+func (s *PersonRepoTestSuite) expectRecord(personID, version int, op string) {
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`INSERT INTO "person_audit" ("person_id","version","operation","actor_id","request_id","changes","created_at") VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING "id"`)).
+		WithArgs(personID, version, op, nil, "", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`INSERT INTO "person_versions" ("person_id","version","name","age","address","work","deleted","created_at") VALUES ($1,$2,$3,$4,$5,$6,$7,$8) RETURNING "id"`)).
+		WithArgs(personID, version, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), op == models.AuditDelete, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+}
+
+// expectLock expects the locking read of a person inside a transaction.
+func (s *PersonRepoTestSuite) expectLock(unscoped bool, rows *sqlmock.Rows, args ...driver.Value) {
+	query := `SELECT * FROM "people" WHERE id = $1 AND "people"."deleted_at" IS NULL LIMIT $2 FOR UPDATE`
+	if unscoped {
+		query = `SELECT * FROM "people" WHERE id = $1 LIMIT $2 FOR UPDATE`
+	}
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(args...).WillReturnRows(rows)
+}
+
 func (s *PersonRepoTestSuite) TestCreatePerson(t provider.T) {
 	person := s.personBuilder.
 		WithID(1).
@@ -74,15 +102,18 @@ func (s *PersonRepoTestSuite) TestCreatePerson(t provider.T) {
 	s.mock.ExpectBegin()
 
 	s.mock.ExpectQuery(regexp.QuoteMeta(
-		`INSERT INTO "people" ("name","age","address","work","id") VALUES ($1,$2,$3,$4,$5) RETURNING "id"`)).
-		WithArgs(person.Name, person.Age, person.Address, person.Work, person.ID).
+		`INSERT INTO "people" ("name","age","address","work","version","deleted_at","id") VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING "id"`)).
+		WithArgs(person.Name, person.Age, person.Address, person.Work, 1, nil, person.ID).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 
+	s.expectRecord(1, 1, models.AuditCreate)
+
 	s.mock.ExpectCommit()
 
-	err := s.repo.Create(&person)
+	err := s.repo.Create(context.Background(), &person)
 	t.Assert().NoError(err)
 	t.Assert().Equal(1, person.ID)
+	t.Assert().Equal(1, person.Version)
 }
 
 func (s *PersonRepoTestSuite) TestGetPerson(t provider.T) {
@@ -92,23 +123,25 @@ func (s *PersonRepoTestSuite) TestGetPerson(t provider.T) {
 		WithAge(20).
 		WithAddress("Address").
 		WithWork("Work").
+		WithVersion(3).
 		Build()
 
-	rows := sqlmock.NewRows([]string{"id", "name", "age", "address", "work"}).
+	rows := sqlmock.NewRows([]string{"id", "name", "age", "address", "work", "version"}).
 		AddRow(
 			person.ID,
 			person.Name,
 			person.Age,
 			person.Address,
 			person.Work,
+			person.Version,
 		)
 
 	s.mock.ExpectQuery(regexp.QuoteMeta(
-		`SELECT * FROM "people" WHERE id = $1 LIMIT $2`)).
+		`SELECT * FROM "people" WHERE id = $1 AND "people"."deleted_at" IS NULL LIMIT $2`)).
 		WithArgs(person.ID, 1).
 		WillReturnRows(rows)
 
-	resPerson, err := s.repo.Get(person.ID)
+	resPerson, err := s.repo.Get(context.Background(), person.ID)
 	t.Assert().NoError(err)
 	t.Assert().Equal(person, *resPerson)
 }
@@ -117,76 +150,523 @@ func (s *PersonRepoTestSuite) TestUpdatePerson(t provider.T) {
 	person := s.personBuilder.
 		WithID(1).
 		WithName("Name").
-		WithAge(20).
+		WithAge(0).
 		WithAddress("Address").
-		WithWork("Work").
+		WithWork("").
+		WithVersion(2).
 		Build()
 
-	rows := sqlmock.NewRows([]string{"id", "name", "age", "address", "work"}).
+	rows := sqlmock.NewRows([]string{"id", "name", "age", "address", "work", "version"}).
 		AddRow(
 			person.ID,
 			person.Name,
 			person.Age,
 			person.Address,
 			person.Work,
+			person.Version,
 		)
 
 	s.mock.ExpectBegin()
 
+	s.expectLock(false, sqlmock.NewRows([]string{"id", "name", "age", "address", "work", "version"}).
+		AddRow(person.ID, person.Name, 20, person.Address, "Work", 1), person.ID, 1)
+
 	s.mock.ExpectQuery(regexp.QuoteMeta(
-		`UPDATE "people" SET "name"=$1,"age"=$2,"address"=$3,"work"=$4 WHERE "id" = $5 RETURNING *`)).
-		WithArgs(person.Name, person.Age, person.Address, person.Work, person.ID).WillReturnRows(rows)
+		`UPDATE "people" SET "age"=$1,"version"=version + 1,"work"=$2 WHERE "id" = $3 RETURNING *`)).
+		WithArgs(person.Age, person.Work, person.ID).WillReturnRows(rows)
+
+	s.expectRecord(person.ID, person.Version, models.AuditUpdate)
 
 	s.mock.ExpectCommit()
 
-	err := s.repo.Update(&person)
+	patch := &models.PersonPatch{ID: person.ID, Age: &person.Age, Work: &person.Work}
+	resPerson, err := s.repo.Update(context.Background(), patch)
 	t.Assert().NoError(err)
+	t.Assert().Equal(&person, resPerson)
 }
 
-func (s *PersonRepoTestSuite) TestDeletePerson(t provider.T) {
+func (s *PersonRepoTestSuite) TestUpdateStaleVersion(t provider.T) {
 	person := s.personBuilder.
 		WithID(1).
+		WithAge(21).
+		WithVersion(3).
+		Build()
+
+	s.mock.ExpectBegin()
+
+	s.expectLock(false, sqlmock.NewRows([]string{"id", "age", "version"}).
+		AddRow(person.ID, 20, person.Version+1), person.ID, 1)
+
+	s.mock.ExpectRollback()
+
+	patch := &models.PersonPatch{ID: person.ID, Version: person.Version, Age: &person.Age}
+	_, err := s.repo.Update(context.Background(), patch)
+	t.Assert().ErrorIs(err, models.ErrPreconditionFailed)
+}
+
+func (s *PersonRepoTestSuite) TestReplaceUpsert(t provider.T) {
+	person := s.personBuilder.
+		WithID(10).
 		WithName("Name").
 		WithAge(20).
 		WithAddress("Address").
 		WithWork("Work").
+		WithVersion(0).
 		Build()
 
+	rows := sqlmock.NewRows([]string{"id"}).AddRow(person.ID)
+
 	s.mock.ExpectBegin()
 
+	s.expectLock(true, sqlmock.NewRows([]string{"id"}), person.ID, 1)
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`INSERT INTO "people" ("name","age","address","work","version","deleted_at","id") VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING "id"`)).
+		WithArgs(person.Name, person.Age, person.Address, person.Work, 1, nil, person.ID).
+		WillReturnRows(rows)
+
 	s.mock.ExpectExec(regexp.QuoteMeta(
-		`DELETE FROM "people" WHERE "people"."id" = $1`)).
-		WithArgs(person.ID).WillReturnResult(sqlmock.NewResult(int64(person.ID), 1))
+		`SELECT setval('people_id_seq', GREATEST(last_value, $1)) FROM people_id_seq`)).
+		WithArgs(person.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	s.expectRecord(person.ID, 1, models.AuditCreate)
 
 	s.mock.ExpectCommit()
 
-	err := s.repo.Delete(person.ID)
+	created, err := s.repo.Replace(context.Background(), &person, true)
+	t.Assert().NoError(err)
+	t.Assert().True(created)
+	t.Assert().Equal(1, person.Version)
+}
+
+func (s *PersonRepoTestSuite) TestReplaceUpsertIfMatchMissing(t provider.T) {
+	person := s.personBuilder.
+		WithID(10).
+		WithName("Name").
+		WithVersion(models.AnyVersion).
+		Build()
+
+	s.mock.ExpectBegin()
+	s.expectLock(true, sqlmock.NewRows([]string{"id"}), person.ID, 1)
+	s.mock.ExpectRollback()
+
+	created, err := s.repo.Replace(context.Background(), &person, true)
+	t.Assert().ErrorIs(err, models.ErrPreconditionFailed)
+	t.Assert().False(created)
+}
+
+func (s *PersonRepoTestSuite) TestDeletePerson(t provider.T) {
+	person := s.personBuilder.
+		WithID(1).
+		WithName("Name").
+		WithAge(20).
+		WithAddress("Address").
+		WithWork("Work").
+		Build()
+
+	s.mock.ExpectBegin()
+
+	s.expectLock(false, sqlmock.NewRows([]string{"id", "name", "version"}).
+		AddRow(person.ID, person.Name, 2), person.ID, 1)
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`UPDATE "people" SET "deleted_at"=$1,"version"=version + 1 WHERE "id" = $2 RETURNING *`)).
+		WithArgs(sqlmock.AnyArg(), person.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "version", "deleted_at"}).AddRow(person.ID, person.Name, 3, time.Now()))
+
+	s.expectRecord(person.ID, 3, models.AuditDelete)
+
+	s.mock.ExpectCommit()
+
+	err := s.repo.Delete(context.Background(), person.ID, 0)
 	t.Assert().NoError(err)
 }
 
 func (s *PersonRepoTestSuite) TestGetAll(t provider.T) {
 	persons := make([]models.Person, 10)
-	for _, person := range persons {
-		err := faker.FakeData(&person)
+	for i := range persons {
+		err := faker.FakeData(&persons[i])
 		t.Assert().NoError(err)
 	}
 
 	personsPtr := make([]*models.Person, len(persons))
-	for i, person := range persons {
-		personsPtr[i] = &person
+	for i := range persons {
+		personsPtr[i] = &persons[i]
 	}
 
-	rowsPersons := sqlmock.NewRows([]string{"id", "name", "age", "address", "work"})
+	rowsPersons := sqlmock.NewRows([]string{"id", "name", "age", "address", "work", "version"})
 
 	for i := range persons {
-		rowsPersons.AddRow(persons[i].ID, persons[i].Name, persons[i].Age, persons[i].Address, persons[i].Work)
+		rowsPersons.AddRow(persons[i].ID, persons[i].Name, persons[i].Age, persons[i].Address, persons[i].Work, persons[i].Version)
 	}
 
 	s.mock.ExpectQuery(regexp.QuoteMeta(
-		`SELECT * FROM "people"`)).
+		`SELECT count(*) FROM "people"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(len(persons)))
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "people" WHERE "people"."deleted_at" IS NULL ORDER BY "id" LIMIT $1`)).
+		WithArgs(11).
 		WillReturnRows(rowsPersons)
 
-	resPersons, err := s.repo.GetAll()
+	resPage, err := s.repo.GetAll(context.Background(), models.PersonQuery{Limit: 10})
+	t.Assert().NoError(err)
+	t.Assert().Equal(personsPtr, resPage.Persons)
+	t.Assert().Equal(int64(len(persons)), resPage.Total)
+	t.Assert().False(resPage.HasNext)
+	t.Assert().False(resPage.HasPrev)
+}
+
+func (s *PersonRepoTestSuite) TestGetAllFiltered(t provider.T) {
+	ageGte, work := 18, "Work"
+	q := models.PersonQuery{
+		Limit:  2,
+		Offset: 4,
+		Sort:   []models.SortField{{Field: "name"}, {Field: "age", Desc: true}},
+		Filter: models.PersonFilter{
+			AgeGte:          &ageGte,
+			Work:            &work,
+			AddressContains: "50%",
+			NamePrefix:      "Jo",
+		},
+	}
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT count(*) FROM "people" WHERE age >= $1 AND work = $2 AND address ILIKE $3 AND name LIKE $4 AND "people"."deleted_at" IS NULL`)).
+		WithArgs(ageGte, work, `%50\%%`, "Jo%").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(10))
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "people" WHERE age >= $1 AND work = $2 AND address ILIKE $3 AND name LIKE $4 AND "people"."deleted_at" IS NULL ORDER BY "name","age" DESC,"id" LIMIT $5 OFFSET $6`)).
+		WithArgs(ageGte, work, `%50\%%`, "Jo%", 3, 4).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "age", "address", "work"}).
+			AddRow(1, "Joe", 30, "50% street", work).
+			AddRow(2, "John", 20, "50% street", work).
+			AddRow(3, "Jon", 40, "50% street", work))
+
+	resPage, err := s.repo.GetAll(context.Background(), q)
+	t.Assert().NoError(err)
+	t.Assert().Len(resPage.Persons, 2)
+	t.Assert().Equal(int64(10), resPage.Total)
+	t.Assert().True(resPage.HasNext)
+	t.Assert().True(resPage.HasPrev)
+}
+
+func (s *PersonRepoTestSuite) TestGetAllCursor(t provider.T) {
+	sort := []models.SortField{{Field: "age", Desc: true}}
+	cursor := models.NewCursor(&models.Person{ID: 5, Age: 30}, sort, true)
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT count(*) FROM "people"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(10))
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "people" WHERE (((age > $1) OR (age = $2 AND id < $3))) AND "people"."deleted_at" IS NULL ORDER BY "age","id" DESC LIMIT $4`)).
+		WithArgs(30, 30, 5, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "age", "address", "work"}).
+			AddRow(4, "A", 30, "", "").
+			AddRow(2, "B", 31, "", ""))
+
+	resPage, err := s.repo.GetAll(context.Background(), models.PersonQuery{Limit: 2, Cursor: cursor, Sort: sort})
+	t.Assert().NoError(err)
+	t.Assert().Equal(2, resPage.Persons[0].ID)
+	t.Assert().Equal(4, resPage.Persons[1].ID)
+	t.Assert().True(resPage.HasNext)
+	t.Assert().False(resPage.HasPrev)
+}
+
+func (s *PersonRepoTestSuite) TestGetAllTrashed(t provider.T) {
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT count(*) FROM "people" WHERE deleted_at IS NOT NULL`)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "people" WHERE deleted_at IS NOT NULL ORDER BY "id" LIMIT $1`)).
+		WithArgs(11).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "deleted_at"}).
+			AddRow(1, "Name", time.Now()))
+
+	resPage, err := s.repo.GetAll(context.Background(), models.PersonQuery{Limit: 10, Trashed: true})
+	t.Assert().NoError(err)
+	t.Require().Len(resPage.Persons, 1)
+	t.Assert().True(resPage.Persons[0].DeletedAt.Valid)
+}
+
+func (s *PersonRepoTestSuite) TestRestorePerson(t provider.T) {
+	s.mock.ExpectBegin()
+
+	s.expectLock(true, sqlmock.NewRows([]string{"id", "name", "version", "deleted_at"}).
+		AddRow(1, "Name", 2, time.Now()), 1, 1)
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`UPDATE "people" SET "deleted_at"=$1,"version"=version + 1 WHERE "id" = $2 RETURNING *`)).
+		WithArgs(nil, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "version", "deleted_at"}).AddRow(1, "Name", 3, nil))
+
+	s.expectRecord(1, 3, models.AuditRestore)
+
+	s.mock.ExpectCommit()
+
+	resPerson, err := s.repo.Restore(context.Background(), 1)
+	t.Assert().NoError(err)
+	t.Assert().Equal(&models.Person{ID: 1, Name: "Name", Version: 3}, resPerson)
+}
+
+func (s *PersonRepoTestSuite) TestPurge(t provider.T) {
+	before := time.Now()
+
+	s.mock.ExpectBegin()
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`DELETE FROM "people" WHERE deleted_at < $1 RETURNING *`)).
+		WithArgs(before).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "version"}).
+			AddRow(1, "A", 2).
+			AddRow(2, "B", 1))
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`INSERT INTO "person_audit" ("person_id","version","operation","actor_id","request_id","changes","created_at") VALUES ($1,$2,$3,$4,$5,$6,$7),($8,$9,$10,$11,$12,$13,$14) RETURNING "id"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+
+	s.mock.ExpectCommit()
+
+	purged, err := s.repo.Purge(context.Background(), before)
+	t.Assert().NoError(err)
+	t.Assert().Equal(int64(2), purged)
+}
+
+func (s *PersonRepoTestSuite) TestHistory(t provider.T) {
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT count(*) FROM "person_audit" WHERE person_id = $1`)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "person_audit" WHERE person_id = $1 ORDER BY id DESC LIMIT $2 OFFSET $3`)).
+		WithArgs(1, 2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "person_id", "version", "operation", "changes"}).
+			AddRow(2, 1, 2, models.AuditUpdate, `{"age":{"before":20,"after":21}}`).
+			AddRow(1, 1, 1, models.AuditCreate, `{}`))
+
+	page, err := s.repo.History(context.Background(), models.AuditQuery{PersonID: 1, Limit: 2, Offset: 1})
 	t.Assert().NoError(err)
-	t.Assert().Equal(personsPtr, resPersons)
+	t.Assert().Equal(int64(3), page.Total)
+	t.Require().Len(page.Entries, 2)
+	t.Assert().Equal(models.AuditUpdate, page.Entries[0].Operation)
+	t.Assert().JSONEq(`21`, string(page.Entries[0].Changes["age"].After))
+}
+
+func (s *PersonRepoTestSuite) TestGetAsOf(t provider.T) {
+	at := time.Now()
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "person_versions" WHERE person_id = $1 AND created_at <= $2 ORDER BY id DESC LIMIT $3`)).
+		WithArgs(1, at, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "person_id", "version", "name", "deleted"}).
+			AddRow(5, 1, 2, "Name", false))
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "person_versions" WHERE person_id = $1 AND created_at <= $2 ORDER BY id DESC LIMIT $3`)).
+		WithArgs(1, at, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "person_id", "version", "name", "deleted"}).
+			AddRow(6, 1, 2, "Name", true))
+
+	resPerson, err := s.repo.GetAsOf(context.Background(), 1, at)
+	t.Assert().NoError(err)
+	t.Assert().Equal(&models.Person{ID: 1, Name: "Name", Version: 2}, resPerson)
+
+	_, err = s.repo.GetAsOf(context.Background(), 1, at)
+	t.Assert().ErrorIs(err, models.ErrNotFound)
+}
+
+func (s *PersonRepoTestSuite) TestGetVersion(t provider.T) {
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "person_versions" WHERE person_id = $1 AND version = $2 AND NOT deleted LIMIT $3`)).
+		WithArgs(1, 3, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err := s.repo.GetVersion(context.Background(), 1, 3)
+	t.Assert().ErrorIs(err, models.ErrNotFound)
+}
+
+func (s *PersonRepoTestSuite) TestBatchAtomic(t provider.T) {
+	ops := []models.BatchOperation{
+		{Op: models.BatchCreate, Person: &models.Person{Name: "A", Age: 20}},
+		{Op: models.BatchDelete, ID: 5},
+		{Op: models.BatchCreate, Person: &models.Person{Name: "B", Age: 30}},
+	}
+
+	s.mock.ExpectBegin()
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`INSERT INTO "people" ("name","age","address","work","version","deleted_at") VALUES ($1,$2,$3,$4,$5,$6),($7,$8,$9,$10,$11,$12) RETURNING "id"`)).
+		WithArgs("A", 20, "", "", 1, nil, "B", 30, "", "", 1, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "person_audit"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "person_versions"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+
+	s.expectLock(false, sqlmock.NewRows([]string{"id"}), 5, 1)
+
+	s.mock.ExpectRollback()
+
+	outcomes, err := s.repo.Batch(context.Background(), ops, true)
+	t.Require().NoError(err)
+	t.Require().Len(outcomes, 3)
+	t.Assert().Equal(1, outcomes[0].Person.ID)
+	t.Assert().ErrorIs(outcomes[1].Err, models.ErrNotFound)
+	t.Assert().Equal(2, outcomes[2].Person.ID)
+}
+
+func (s *PersonRepoTestSuite) TestBatchBestEffort(t provider.T) {
+	age := 21
+	ops := []models.BatchOperation{
+		{Op: models.BatchUpdate, Patch: &models.PersonPatch{ID: 1, Version: 2, Age: &age}},
+	}
+
+	s.mock.ExpectBegin()
+
+	s.mock.ExpectExec(regexp.QuoteMeta(`SAVEPOINT batch_operation`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	s.expectLock(false, sqlmock.NewRows([]string{"id", "version"}).AddRow(1, 3), 1, 1)
+
+	s.mock.ExpectExec(regexp.QuoteMeta(`ROLLBACK TO SAVEPOINT batch_operation`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	s.mock.ExpectCommit()
+
+	outcomes, err := s.repo.Batch(context.Background(), ops, false)
+	t.Require().NoError(err)
+	t.Require().Len(outcomes, 1)
+	t.Assert().ErrorIs(outcomes[0].Err, models.ErrPreconditionFailed)
+	t.Assert().Nil(outcomes[0].Person)
+}
+
+func (s *PersonRepoTestSuite) TestEach(t provider.T) {
+	ageGte := 18
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "people" WHERE age >= $1 AND "people"."deleted_at" IS NULL ORDER BY "people"."id" LIMIT $2`)).
+		WithArgs(ageGte, eachBatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "age"}).
+			AddRow(1, "Name", 20).
+			AddRow(2, "Name", 30))
+
+	var ids []int
+	err := s.repo.Each(context.Background(), models.PersonFilter{AgeGte: &ageGte}, func(p *models.Person) error {
+		ids = append(ids, p.ID)
+		return nil
+	})
+	t.Assert().NoError(err)
+	t.Assert().Equal([]int{1, 2}, ids)
+}
+
+func (s *PersonRepoTestSuite) TestEachPage(t provider.T) {
+	sort := []models.SortField{{Field: "age", Desc: true}}
+	cursor := models.NewCursor(&models.Person{ID: 5, Age: 30}, sort, true)
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT count(*) FROM "people"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(10))
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT "age","id" FROM "people" WHERE (((age > $1) OR (age = $2 AND id < $3))) AND "people"."deleted_at" IS NULL ORDER BY "age","id" DESC LIMIT $4`)).
+		WithArgs(30, 30, 5, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"age", "id"}).
+			AddRow(30, 4).
+			AddRow(31, 2))
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "people" WHERE id IN ($1,$2) AND "people"."deleted_at" IS NULL ORDER BY "age" DESC,"id"`)).
+		WithArgs(2, 4).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "age", "address", "work"}).
+			AddRow(2, "B", 31, "", "").
+			AddRow(4, "A", 30, "", ""))
+
+	var (
+		page *models.PersonPage
+		ids  []int
+	)
+	err := s.repo.EachPage(context.Background(), models.PersonQuery{Limit: 2, Cursor: cursor, Sort: sort}, func(p *models.PersonPage) error {
+		page = p
+		return nil
+	}, func(p *models.Person) error {
+		ids = append(ids, p.ID)
+		return nil
+	})
+	t.Require().NoError(err)
+	t.Assert().Equal([]int{2, 4}, ids)
+	t.Assert().Equal(&models.Person{ID: 2, Age: 31}, page.First)
+	t.Assert().Equal(&models.Person{ID: 4, Age: 30}, page.Last)
+	t.Assert().True(page.HasNext)
+	t.Assert().False(page.HasPrev)
+}
+
+func (s *PersonRepoTestSuite) TestGetPersonNotFound(t provider.T) {
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "people" WHERE id = $1 AND "people"."deleted_at" IS NULL LIMIT $2`)).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "age", "address", "work"}))
+
+	_, err := s.repo.Get(context.Background(), 1)
+	t.Assert().ErrorIs(err, models.ErrNotFound)
+}
+
+func (s *PersonRepoTestSuite) TestCreatePersonErrors(t provider.T) {
+	cases := map[string]struct {
+		DBError error
+		Error   error
+	}{
+		"unique violation": {
+			DBError: &pgconn.PgError{Code: "23505"},
+			Error:   models.ErrConflict,
+		},
+		"value too long": {
+			DBError: &pgconn.PgError{Code: "22001"},
+			Error:   models.ErrValidation,
+		},
+		"connection lost": {
+			DBError: &pgconn.PgError{Code: "08006"},
+			Error:   models.ErrUnavailable,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t provider.T) {
+			person := s.personBuilder.WithID(1).WithName("Name").Build()
+
+			s.mock.ExpectBegin()
+			s.mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "people"`)).
+				WillReturnError(test.DBError)
+			s.mock.ExpectRollback()
+
+			err := s.repo.Create(context.Background(), &person)
+			t.Assert().ErrorIs(err, test.Error)
+		})
+	}
+}
+
+func (s *PersonRepoTestSuite) TestGetPersonCanceled(t provider.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := s.repo.Get(ctx, 1)
+	t.Assert().ErrorIs(err, context.Canceled)
+}
+
+func (s *PersonRepoTestSuite) TestHealthCheck(t provider.T) {
+	check := HealthCheck(s.gormDB)
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(`SELECT to_regclass('people') IS NOT NULL`)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	t.Assert().Error(check.Check(context.Background()))
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(`SELECT to_regclass('people') IS NOT NULL`)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	t.Assert().NoError(check.Check(context.Background()))
 }
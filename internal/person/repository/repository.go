@@ -1,11 +1,53 @@
 package repository
 
-import "github.com/Davmie/person_service/models"
+import (
+	"context"
+	"time"
 
+	"github.com/Davmie/person_service/models"
+)
+
+// PersonRepositoryI writes an audit entry and a snapshot of the person for
+// every change in the same transaction as the change.
 type PersonRepositoryI interface {
-	Create(p *models.Person) error
-	Get(id int) (*models.Person, error)
-	Update(p *models.Person) error
-	Delete(id int) error
-	GetAll() ([]*models.Person, error)
+	Create(ctx context.Context, p *models.Person) error
+	Get(ctx context.Context, id int) (*models.Person, error)
+	// Update and Delete act only on the given version of the person when it
+	// is non-zero and fail with models.ErrPreconditionFailed if it changed.
+	// Update writes the set fields of the patch, increments the version and
+	// returns the stored person.
+	Update(ctx context.Context, patch *models.PersonPatch) (*models.Person, error)
+	// Delete moves the person to the trash.
+	Delete(ctx context.Context, id, version int) error
+	// Replace overwrites every field of the person with p and fills p with
+	// the stored row. With upsert and no version a missing person is created
+	// at p.ID; created reports whether that happened. Replacing a trashed
+	// person fails with models.ErrConflict.
+	Replace(ctx context.Context, p *models.Person, upsert bool) (created bool, err error)
+	GetAll(ctx context.Context, q models.PersonQuery) (*models.PersonPage, error)
+	// EachPage runs q like GetAll but hands the persons of the page to fn one
+	// at a time. start gets the page, with First and Last set instead of
+	// Persons, before fn is first called.
+	EachPage(ctx context.Context, q models.PersonQuery, start func(page *models.PersonPage) error, fn func(p *models.Person) error) error
+	// Each calls fn for every person matching f in ID order, without holding
+	// all of them in memory. It stops at the first error fn returns.
+	Each(ctx context.Context, f models.PersonFilter, fn func(p *models.Person) error) error
+	// Restore takes a person out of the trash and increments its version.
+	Restore(ctx context.Context, id int) (*models.Person, error)
+	// Purge permanently removes the persons trashed before the given time.
+	Purge(ctx context.Context, before time.Time) (int64, error)
+	// History returns the audit entries of a person, newest first. They
+	// outlive the person.
+	History(ctx context.Context, q models.AuditQuery) (*models.AuditPage, error)
+	// GetAsOf returns the person as it was at the given time. A person that
+	// did not exist yet or was in the trash then is not found.
+	GetAsOf(ctx context.Context, id int, at time.Time) (*models.Person, error)
+	// GetVersion returns the given version of the person.
+	GetVersion(ctx context.Context, id, version int) (*models.Person, error)
+	// Batch applies ops in one transaction: the creates together, then the
+	// updates and deletes in order. outcomes[i] is the outcome of ops[i].
+	// With atomic nothing is committed once an operation fails, and the
+	// outcome of that operation holds its error; otherwise only the failed
+	// operations are undone.
+	Batch(ctx context.Context, ops []models.BatchOperation, atomic bool) (outcomes []models.BatchOutcome, err error)
 }
@@ -0,0 +1,49 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/Davmie/person_service/pkg/logger"
+)
+
+// Purger periodically removes persons that have been in the trash for
+// longer than Retention.
+type Purger struct {
+	UseCase   PersonUseCaseI
+	Retention time.Duration
+	Interval  time.Duration
+	Logger    logger.Logger
+}
+
+// Run purges once right away and then every Interval until ctx is done.
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		p.purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Purger) purge(ctx context.Context) {
+	purged, err := p.UseCase.PurgeTrash(ctx, time.Now().Add(-p.Retention))
+	if err != nil {
+		if ctx.Err() == nil {
+			p.Logger.Errorw("can`t purge trash",
+				"err:", err.Error())
+		}
+		return
+	}
+
+	if purged > 0 {
+		p.Logger.Infow("purged trash",
+			"persons", purged)
+	}
+}
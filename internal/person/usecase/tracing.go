@@ -0,0 +1,179 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/Davmie/person_service/models"
+	"github.com/Davmie/person_service/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracingPersonUseCase struct {
+	next PersonUseCaseI
+}
+
+// WithTracing wraps every call to uc in a span named after the method.
+func WithTracing(uc PersonUseCaseI) PersonUseCaseI {
+	return &tracingPersonUseCase{next: uc}
+}
+
+func (tuc *tracingPersonUseCase) Create(ctx context.Context, p *models.Person) error {
+	ctx, span := tracing.Tracer().Start(ctx, "personUseCase.Create")
+	err := tuc.next.Create(ctx, p)
+	span.SetAttributes(attribute.Int("person.id", p.ID))
+	tracing.End(span, err)
+
+	return err
+}
+
+func (tuc *tracingPersonUseCase) Get(ctx context.Context, id int) (*models.Person, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "personUseCase.Get", personAttr(id))
+	p, err := tuc.next.Get(ctx, id)
+	tracing.End(span, err)
+
+	return p, err
+}
+
+func (tuc *tracingPersonUseCase) Update(ctx context.Context, patch *models.PersonPatch) (*models.Person, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "personUseCase.Update", personAttr(patch.ID))
+	p, err := tuc.next.Update(ctx, patch)
+	tracing.End(span, err)
+
+	return p, err
+}
+
+func (tuc *tracingPersonUseCase) ApplyPatch(ctx context.Context, id, version int, ops models.JSONPatch) (*models.Person, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "personUseCase.ApplyPatch",
+		trace.WithAttributes(attribute.Int("person.id", id), attribute.Int("patch.operations", len(ops))))
+	p, err := tuc.next.ApplyPatch(ctx, id, version, ops)
+	tracing.End(span, err)
+
+	return p, err
+}
+
+func (tuc *tracingPersonUseCase) Delete(ctx context.Context, id, version int) error {
+	ctx, span := tracing.Tracer().Start(ctx, "personUseCase.Delete", personAttr(id))
+	err := tuc.next.Delete(ctx, id, version)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (tuc *tracingPersonUseCase) Replace(ctx context.Context, p *models.Person, upsert bool) (bool, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "personUseCase.Replace", personAttr(p.ID))
+	created, err := tuc.next.Replace(ctx, p, upsert)
+	span.SetAttributes(attribute.Bool("person.created", created))
+	tracing.End(span, err)
+
+	return created, err
+}
+
+func (tuc *tracingPersonUseCase) GetAll(ctx context.Context, q models.PersonQuery) (*models.PersonPage, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "personUseCase.GetAll",
+		trace.WithAttributes(attribute.Int("page.limit", q.Limit), attribute.Int("page.offset", q.Offset)))
+	page, err := tuc.next.GetAll(ctx, q)
+	tracing.End(span, err)
+
+	return page, err
+}
+
+func (tuc *tracingPersonUseCase) EachPage(ctx context.Context, q models.PersonQuery, start func(page *models.PersonPage) error, fn func(p *models.Person) error) error {
+	ctx, span := tracing.Tracer().Start(ctx, "personUseCase.EachPage",
+		trace.WithAttributes(attribute.Int("page.limit", q.Limit), attribute.Int("page.offset", q.Offset)))
+	listed := 0
+	err := tuc.next.EachPage(ctx, q, start, func(p *models.Person) error {
+		listed++
+		return fn(p)
+	})
+	span.SetAttributes(attribute.Int("page.persons", listed))
+	tracing.End(span, err)
+
+	return err
+}
+
+func (tuc *tracingPersonUseCase) Restore(ctx context.Context, id int) (*models.Person, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "personUseCase.Restore", personAttr(id))
+	p, err := tuc.next.Restore(ctx, id)
+	tracing.End(span, err)
+
+	return p, err
+}
+
+func (tuc *tracingPersonUseCase) PurgeTrash(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "personUseCase.PurgeTrash")
+	purged, err := tuc.next.PurgeTrash(ctx, before)
+	span.SetAttributes(attribute.Int64("trash.purged", purged))
+	tracing.End(span, err)
+
+	return purged, err
+}
+
+func (tuc *tracingPersonUseCase) History(ctx context.Context, q models.AuditQuery) (*models.AuditPage, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "personUseCase.History", personAttr(q.PersonID))
+	page, err := tuc.next.History(ctx, q)
+	tracing.End(span, err)
+
+	return page, err
+}
+
+func (tuc *tracingPersonUseCase) GetAsOf(ctx context.Context, id int, at time.Time) (*models.Person, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "personUseCase.GetAsOf", personAttr(id))
+	p, err := tuc.next.GetAsOf(ctx, id, at)
+	tracing.End(span, err)
+
+	return p, err
+}
+
+func (tuc *tracingPersonUseCase) Revert(ctx context.Context, id, version, expected int) (*models.Person, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "personUseCase.Revert", personAttr(id))
+	span.SetAttributes(attribute.Int("person.revert_to", version))
+	p, err := tuc.next.Revert(ctx, id, version, expected)
+	tracing.End(span, err)
+
+	return p, err
+}
+
+func (tuc *tracingPersonUseCase) Batch(ctx context.Context, b *models.Batch) ([]models.BatchOutcome, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "personUseCase.Batch", trace.WithAttributes(
+		attribute.Int("batch.operations", len(b.Operations)),
+		attribute.Bool("batch.atomic", b.Atomic),
+	))
+	outcomes, err := tuc.next.Batch(ctx, b)
+	tracing.End(span, err)
+
+	return outcomes, err
+}
+
+func (tuc *tracingPersonUseCase) Export(ctx context.Context, f models.PersonFilter, fn func(p *models.Person) error) error {
+	ctx, span := tracing.Tracer().Start(ctx, "personUseCase.Export")
+	exported := 0
+	err := tuc.next.Export(ctx, f, func(p *models.Person) error {
+		exported++
+		return fn(p)
+	})
+	span.SetAttributes(attribute.Int("export.persons", exported))
+	tracing.End(span, err)
+
+	return err
+}
+
+func (tuc *tracingPersonUseCase) Import(ctx context.Context, next func() (*models.ImportRow, error), dryRun bool) (*models.ImportReport, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "personUseCase.Import", trace.WithAttributes(attribute.Bool("import.dry_run", dryRun)))
+	report, err := tuc.next.Import(ctx, next, dryRun)
+	if report != nil {
+		span.SetAttributes(
+			attribute.Int("import.rows", report.Rows),
+			attribute.Int("import.imported", report.Imported),
+			attribute.Int("import.rejected", report.Rejected),
+		)
+	}
+	tracing.End(span, err)
+
+	return report, err
+}
+
+func personAttr(id int) trace.SpanStartOption {
+	return trace.WithAttributes(attribute.Int("person.id", id))
+}
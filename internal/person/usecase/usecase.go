@@ -1,17 +1,56 @@
 package usecase
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
 	personRep "github.com/Davmie/person_service/internal/person/repository"
 	"github.com/Davmie/person_service/models"
+	"github.com/Davmie/person_service/pkg/validator"
 	"github.com/pkg/errors"
 )
 
 type PersonUseCaseI interface {
-	Create(p *models.Person) error
-	Get(id int) (*models.Person, error)
-	Update(p *models.Person) error
-	Delete(id int) error
-	GetAll() ([]*models.Person, error)
+	Create(ctx context.Context, p *models.Person) error
+	Get(ctx context.Context, id int) (*models.Person, error)
+	Update(ctx context.Context, patch *models.PersonPatch) (*models.Person, error)
+	// ApplyPatch evaluates a JSON Patch against the stored person and writes
+	// the result. A non-zero version must match the stored one.
+	ApplyPatch(ctx context.Context, id, version int, ops models.JSONPatch) (*models.Person, error)
+	Delete(ctx context.Context, id, version int) error
+	// Replace overwrites the person with p. With upsert a missing person is
+	// created at p.ID and created is true.
+	Replace(ctx context.Context, p *models.Person, upsert bool) (created bool, err error)
+	GetAll(ctx context.Context, q models.PersonQuery) (*models.PersonPage, error)
+	// EachPage lists the page q selects like GetAll, handing the page to
+	// start and then its persons to fn one at a time.
+	EachPage(ctx context.Context, q models.PersonQuery, start func(page *models.PersonPage) error, fn func(p *models.Person) error) error
+	Restore(ctx context.Context, id int) (*models.Person, error)
+	// PurgeTrash permanently removes the persons trashed before the given
+	// time and returns how many there were.
+	PurgeTrash(ctx context.Context, before time.Time) (int64, error)
+	// History returns the audit trail of a person, newest first. It outlives
+	// the person, so purged persons still have one.
+	History(ctx context.Context, q models.AuditQuery) (*models.AuditPage, error)
+	GetAsOf(ctx context.Context, id int, at time.Time) (*models.Person, error)
+	// Revert writes the fields of an earlier version of the person back as an
+	// update. A non-zero expected must match the current version.
+	Revert(ctx context.Context, id, version, expected int) (*models.Person, error)
+	// Batch validates and applies the operations of b. outcomes[i] is the
+	// outcome of b.Operations[i]. When an atomic batch fails, the operations
+	// that did not fail themselves hold ErrBatchAborted.
+	Batch(ctx context.Context, b *models.Batch) (outcomes []models.BatchOutcome, err error)
+	// Export calls fn for every person matching f in ID order.
+	Export(ctx context.Context, f models.PersonFilter, fn func(p *models.Person) error) error
+	// Import validates the rows next yields until io.EOF and creates the
+	// valid ones, unless dryRun is set. Rows are written in chunks of
+	// MaxBatchOperations, each in its own transaction. When next or a write
+	// fails, the rows read before are still written and the error comes
+	// with the report, whose Stopped tells where the import ended. next may
+	// return the row it failed on, for its line.
+	Import(ctx context.Context, next func() (*models.ImportRow, error), dryRun bool) (*models.ImportReport, error)
 }
 
 type personUseCase struct {
@@ -24,8 +63,19 @@ func New(aRep personRep.PersonRepositoryI) PersonUseCaseI {
 	}
 }
 
-func (pUC *personUseCase) Create(p *models.Person) error {
-	err := pUC.personRepository.Create(p)
+// current gets the person a write expecting version applies to. When it is
+// missing, a write that expects it to exist fails its precondition.
+func (pUC *personUseCase) current(ctx context.Context, id, version int) (*models.Person, error) {
+	p, err := pUC.personRepository.Get(ctx, id)
+	if errors.Is(err, models.ErrNotFound) && models.ExpectsExisting(version) {
+		return nil, models.ErrPreconditionFailed
+	}
+
+	return p, err
+}
+
+func (pUC *personUseCase) Create(ctx context.Context, p *models.Person) error {
+	err := pUC.personRepository.Create(ctx, p)
 
 	if err != nil {
 		return errors.Wrap(err, "personUseCase.Create error")
@@ -34,8 +84,8 @@ func (pUC *personUseCase) Create(p *models.Person) error {
 	return nil
 }
 
-func (pUC *personUseCase) Get(id int) (*models.Person, error) {
-	resPerson, err := pUC.personRepository.Get(id)
+func (pUC *personUseCase) Get(ctx context.Context, id int) (*models.Person, error) {
+	resPerson, err := pUC.personRepository.Get(ctx, id)
 
 	if err != nil {
 		return nil, errors.Wrap(err, "personUseCase.Get error")
@@ -44,30 +94,60 @@ func (pUC *personUseCase) Get(id int) (*models.Person, error) {
 	return resPerson, nil
 }
 
-func (pUC *personUseCase) Update(p *models.Person) error {
-	_, err := pUC.personRepository.Get(p.ID)
+func (pUC *personUseCase) Update(ctx context.Context, patch *models.PersonPatch) (*models.Person, error) {
+	_, err := pUC.current(ctx, patch.ID, patch.Version)
 
 	if err != nil {
-		return errors.Wrap(err, "personUseCase.Update error: Person not found")
+		return nil, errors.Wrap(err, "personUseCase.Update error: Person not found")
 	}
 
-	err = pUC.personRepository.Update(p)
+	resPerson, err := pUC.personRepository.Update(ctx, patch)
 
 	if err != nil {
-		return errors.Wrap(err, "personUseCase.Update error: Can't update in repo")
+		return nil, errors.Wrap(err, "personUseCase.Update error: Can't update in repo")
 	}
 
-	return nil
+	return resPerson, nil
+}
+
+func (pUC *personUseCase) ApplyPatch(ctx context.Context, id, version int, ops models.JSONPatch) (*models.Person, error) {
+	current, err := pUC.current(ctx, id, version)
+	if err != nil {
+		return nil, errors.Wrap(err, "personUseCase.ApplyPatch error: Person not found")
+	}
+
+	if !models.MatchesVersion(version, current.Version) {
+		return nil, errors.Wrap(models.ErrPreconditionFailed, "personUseCase.ApplyPatch error")
+	}
+
+	patch, err := ops.Apply(current)
+	if err != nil {
+		return nil, errors.Wrap(err, "personUseCase.ApplyPatch error")
+	}
+
+	if errs := patch.Validate(); errs != nil {
+		return nil, errors.Wrap(errs, "personUseCase.ApplyPatch error")
+	}
+
+	// The operations were evaluated against this version, so the write must
+	// not apply to a later one.
+	patch.Version = current.Version
+	resPerson, err := pUC.personRepository.Update(ctx, patch)
+	if err != nil {
+		return nil, errors.Wrap(err, "personUseCase.ApplyPatch error: Can't update in repo")
+	}
+
+	return resPerson, nil
 }
 
-func (pUC *personUseCase) Delete(id int) error {
-	_, err := pUC.personRepository.Get(id)
+func (pUC *personUseCase) Delete(ctx context.Context, id, version int) error {
+	_, err := pUC.current(ctx, id, version)
 
 	if err != nil {
 		return errors.Wrap(err, "personUseCase.Delete error: Person not found")
 	}
 
-	err = pUC.personRepository.Delete(id)
+	err = pUC.personRepository.Delete(ctx, id, version)
 
 	if err != nil {
 		return errors.Wrap(err, "personUseCase.Delete error: Can't delete in repo")
@@ -76,11 +156,278 @@ func (pUC *personUseCase) Delete(id int) error {
 	return nil
 }
 
-func (pUC *personUseCase) GetAll() ([]*models.Person, error) {
-	persons, err := pUC.personRepository.GetAll()
+func (pUC *personUseCase) Replace(ctx context.Context, p *models.Person, upsert bool) (bool, error) {
+	if upsert && p.ID <= 0 {
+		return false, errors.Wrap(validator.Errors{"id": "must be positive"}, "personUseCase.Replace error")
+	}
+
+	created, err := pUC.personRepository.Replace(ctx, p, upsert)
+
+	if err != nil {
+		return false, errors.Wrap(err, "personUseCase.Replace error: Can't replace in repo")
+	}
+
+	return created, nil
+}
+
+func (pUC *personUseCase) GetAll(ctx context.Context, q models.PersonQuery) (*models.PersonPage, error) {
+	page, err := pUC.personRepository.GetAll(ctx, pageLimit(q))
 	if err != nil {
 		return nil, errors.Wrap(err, "personUseCase.GetAll error")
 	}
 
-	return persons, nil
+	return page, nil
+}
+
+func (pUC *personUseCase) EachPage(ctx context.Context, q models.PersonQuery, start func(page *models.PersonPage) error, fn func(p *models.Person) error) error {
+	err := pUC.personRepository.EachPage(ctx, pageLimit(q), start, fn)
+	if err != nil {
+		return errors.Wrap(err, "personUseCase.EachPage error")
+	}
+
+	return nil
+}
+
+// pageLimit applies the default and the maximum page size to q.
+func pageLimit(q models.PersonQuery) models.PersonQuery {
+	if q.Limit <= 0 {
+		q.Limit = models.DefaultPageLimit
+	}
+	if q.Limit > models.MaxPageLimit {
+		q.Limit = models.MaxPageLimit
+	}
+
+	return q
+}
+
+func (pUC *personUseCase) Restore(ctx context.Context, id int) (*models.Person, error) {
+	resPerson, err := pUC.personRepository.Restore(ctx, id)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "personUseCase.Restore error")
+	}
+
+	return resPerson, nil
+}
+
+func (pUC *personUseCase) PurgeTrash(ctx context.Context, before time.Time) (int64, error) {
+	purged, err := pUC.personRepository.Purge(ctx, before)
+
+	if err != nil {
+		return 0, errors.Wrap(err, "personUseCase.PurgeTrash error")
+	}
+
+	return purged, nil
+}
+
+func (pUC *personUseCase) History(ctx context.Context, q models.AuditQuery) (*models.AuditPage, error) {
+	if q.Limit <= 0 {
+		q.Limit = models.DefaultPageLimit
+	}
+	if q.Limit > models.MaxPageLimit {
+		q.Limit = models.MaxPageLimit
+	}
+
+	page, err := pUC.personRepository.History(ctx, q)
+	if err != nil {
+		return nil, errors.Wrap(err, "personUseCase.History error")
+	}
+
+	// Every person has at least its create entry.
+	if page.Total == 0 {
+		return nil, errors.Wrap(models.ErrNotFound, "personUseCase.History error")
+	}
+
+	return page, nil
+}
+
+func (pUC *personUseCase) GetAsOf(ctx context.Context, id int, at time.Time) (*models.Person, error) {
+	resPerson, err := pUC.personRepository.GetAsOf(ctx, id, at)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "personUseCase.GetAsOf error")
+	}
+
+	return resPerson, nil
+}
+
+func (pUC *personUseCase) Revert(ctx context.Context, id, version, expected int) (*models.Person, error) {
+	old, err := pUC.personRepository.GetVersion(ctx, id, version)
+	if err != nil {
+		return nil, errors.Wrap(err, "personUseCase.Revert error: Version not found")
+	}
+
+	patch := old.Patch()
+	patch.Version = expected
+	resPerson, err := pUC.Update(ctx, patch)
+	if err != nil {
+		return nil, errors.Wrap(err, "personUseCase.Revert error")
+	}
+
+	return resPerson, nil
+}
+
+func (pUC *personUseCase) Batch(ctx context.Context, b *models.Batch) ([]models.BatchOutcome, error) {
+	switch {
+	case len(b.Operations) == 0:
+		return nil, errors.Wrap(validator.Errors{"operations": "must not be empty"}, "personUseCase.Batch error")
+	case len(b.Operations) > models.MaxBatchOperations:
+		return nil, errors.Wrap(validator.Errors{
+			"operations": fmt.Sprintf("must have at most %d items", models.MaxBatchOperations),
+		}, "personUseCase.Batch error")
+	}
+
+	outcomes := make([]models.BatchOutcome, len(b.Operations))
+	ops := make([]models.BatchOperation, 0, len(b.Operations))
+	indexes := make([]int, 0, len(b.Operations))
+	for i := range b.Operations {
+		if errs := b.Operations[i].Validate(); errs != nil {
+			outcomes[i].Err = errs
+			continue
+		}
+		ops = append(ops, b.Operations[i])
+		indexes = append(indexes, i)
+	}
+
+	if !b.Atomic || len(ops) == len(b.Operations) {
+		applied, err := pUC.personRepository.Batch(ctx, ops, b.Atomic)
+		if err != nil {
+			return nil, errors.Wrap(err, "personUseCase.Batch error")
+		}
+		for n, i := range indexes {
+			outcomes[i] = applied[n]
+		}
+	}
+
+	if b.Atomic {
+		abortUnlessFailed(outcomes)
+	}
+
+	return outcomes, nil
+}
+
+// abortUnlessFailed marks every outcome as aborted once one of them failed,
+// as nothing of an atomic batch is applied then.
+func abortUnlessFailed(outcomes []models.BatchOutcome) {
+	failed := false
+	for _, o := range outcomes {
+		failed = failed || o.Err != nil
+	}
+	if !failed {
+		return
+	}
+
+	for i := range outcomes {
+		if outcomes[i].Err == nil {
+			outcomes[i] = models.BatchOutcome{Err: models.ErrBatchAborted}
+		}
+	}
+}
+
+func (pUC *personUseCase) Export(ctx context.Context, f models.PersonFilter, fn func(p *models.Person) error) error {
+	err := pUC.personRepository.Each(ctx, f, fn)
+
+	if err != nil {
+		return errors.Wrap(err, "personUseCase.Export error")
+	}
+
+	return nil
+}
+
+func (pUC *personUseCase) Import(ctx context.Context, next func() (*models.ImportRow, error), dryRun bool) (*models.ImportReport, error) {
+	report := &models.ImportReport{DryRun: dryRun, Rejections: []models.ImportRejection{}}
+
+	var (
+		ops   []models.BatchOperation
+		lines []int
+	)
+	flush := func() error {
+		if len(ops) == 0 {
+			return nil
+		}
+
+		outcomes, err := pUC.personRepository.Batch(ctx, ops, false)
+		if err != nil {
+			report.Stopped = &models.ImportStop{Line: lines[0]}
+			return errors.Wrap(err, "personUseCase.Import error: Can't create in repo")
+		}
+		for i, o := range outcomes {
+			if o.Err == nil {
+				report.Imported++
+				continue
+			}
+
+			report.Reject(lines[i], rowErrors(o.Err))
+		}
+
+		ops, lines = nil, nil
+		return nil
+	}
+
+	for {
+		row, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			report.Stopped = &models.ImportStop{}
+			if row != nil {
+				report.Stopped.Line = row.Line
+			}
+			if flushErr := flush(); flushErr != nil {
+				return report, flushErr
+			}
+			return report, errors.Wrap(err, "personUseCase.Import error: Can't read row")
+		}
+
+		report.Rows++
+		errs := row.Errors
+		if errs == nil {
+			errs = row.Person.Validate()
+		}
+		if errs != nil {
+			report.Reject(row.Line, errs)
+			continue
+		}
+
+		row.Person.ID, row.Person.Version = 0, 0
+		if dryRun {
+			report.Imported++
+			if len(report.Preview) < models.ImportPreviewSize {
+				report.Preview = append(report.Preview, row.Person)
+			}
+			continue
+		}
+
+		ops = append(ops, models.BatchOperation{Op: models.BatchCreate, Person: row.Person})
+		lines = append(lines, row.Line)
+		if len(ops) == models.MaxBatchOperations {
+			if err = flush(); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// rowErrors tells why the repository refused a row. The other rows of its
+// chunk are stored regardless, so unexpected errors reject the row too.
+func rowErrors(err error) validator.Errors {
+	var errs validator.Errors
+	if errors.As(err, &errs) {
+		return errs
+	}
+
+	for _, kind := range []error{models.ErrValidation, models.ErrConflict} {
+		if errors.Is(err, kind) {
+			return validator.Errors{"row": kind.Error()}
+		}
+	}
+
+	return validator.Errors{"row": "can`t be stored"}
 }
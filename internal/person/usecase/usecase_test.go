@@ -1,14 +1,20 @@
 package usecase
 
 import (
+	"context"
 	personMocks "github.com/Davmie/person_service/internal/person/repository/mocks"
 	"github.com/Davmie/person_service/internal/testBuilders"
 	"github.com/Davmie/person_service/models"
+	"github.com/Davmie/person_service/pkg/validator"
 	"github.com/bxcodec/faker"
 	"github.com/ozontech/allure-go/pkg/framework/provider"
 	"github.com/ozontech/allure-go/pkg/framework/suite"
 	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	"io"
 	"testing"
+	"time"
 )
 
 type PersonTestSuite struct {
@@ -36,8 +42,8 @@ func (s *PersonTestSuite) TestCreatePerson(t provider.T) {
 		WithWork("Work").
 		Build()
 
-	s.personRepoMock.On("Create", &person).Return(nil)
-	err := s.uc.Create(&person)
+	s.personRepoMock.On("Create", mock.Anything, &person).Return(nil)
+	err := s.uc.Create(context.Background(), &person)
 
 	t.Assert().NoError(err)
 	t.Assert().Equal(person.ID, 1)
@@ -54,33 +60,148 @@ func (s *PersonTestSuite) TestUpdatePerson(t provider.T) {
 
 	notFoundPerson := s.personBuilder.WithID(0).Build()
 
-	s.personRepoMock.On("Get", person.ID).Return(&person, nil)
-	s.personRepoMock.On("Update", &person).Return(nil)
-	s.personRepoMock.On("Get", notFoundPerson.ID).Return(&notFoundPerson, errors.Wrap(err, "Person not found"))
-	s.personRepoMock.On("Update", &notFoundPerson).Return(errors.Wrap(err, "Person not found"))
+	patch := &models.PersonPatch{ID: person.ID, Age: &person.Age}
+	notFoundPatch := &models.PersonPatch{ID: notFoundPerson.ID}
+
+	s.personRepoMock.On("Get", mock.Anything, person.ID).Return(&person, nil)
+	s.personRepoMock.On("Update", mock.Anything, patch).Return(&person, nil)
+	s.personRepoMock.On("Get", mock.Anything, notFoundPerson.ID).Return(&notFoundPerson, errors.Wrap(err, "Person not found"))
+	s.personRepoMock.On("Update", mock.Anything, notFoundPatch).Return(nil, errors.Wrap(err, "Person not found"))
 
 	cases := map[string]struct {
-		ArgData *models.Person
+		ArgData *models.PersonPatch
 		Error   error
 	}{
 		"success": {
-			ArgData: &person,
+			ArgData: patch,
 			Error:   nil,
 		},
 		"Person not found": {
-			ArgData: &notFoundPerson,
+			ArgData: notFoundPatch,
 			Error:   errors.Wrap(err, "Person not found"),
 		},
 	}
 
 	for name, test := range cases {
 		t.Run(name, func(t provider.T) {
-			err := s.uc.Update(test.ArgData)
+			_, err := s.uc.Update(context.Background(), test.ArgData)
 			t.Assert().ErrorIs(err, test.Error)
 		})
 	}
 }
 
+func (s *PersonTestSuite) TestApplyPatch(t provider.T) {
+	person := s.personBuilder.WithID(1).
+		WithName("Name").
+		WithAge(20).
+		WithAddress("Address").
+		WithWork("Work").
+		WithVersion(3).
+		Build()
+
+	age := 0
+	patch := &models.PersonPatch{ID: person.ID, Version: person.Version, Age: &age}
+	updated := person
+	updated.Age = 0
+	updated.Version++
+
+	s.personRepoMock.On("Get", mock.Anything, person.ID).Return(&person, nil)
+	s.personRepoMock.On("Update", mock.Anything, patch).Return(&updated, nil).Once()
+
+	cases := map[string]struct {
+		Version int
+		Ops     models.JSONPatch
+		Error   error
+	}{
+		"success": {
+			Ops: models.JSONPatch{
+				{Op: "test", Path: "/name", Value: []byte(`"Name"`)},
+				{Op: "remove", Path: "/age"},
+			},
+		},
+		"test failed": {
+			Ops:   models.JSONPatch{{Op: "test", Path: "/name", Value: []byte(`"Other"`)}},
+			Error: models.ErrPatchTestFailed,
+		},
+		"stale version": {
+			Version: 2,
+			Ops:     models.JSONPatch{{Op: "remove", Path: "/age"}},
+			Error:   models.ErrPreconditionFailed,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t provider.T) {
+			_, err := s.uc.ApplyPatch(context.Background(), person.ID, test.Version, test.Ops)
+			t.Assert().ErrorIs(err, test.Error)
+		})
+	}
+}
+
+func (s *PersonTestSuite) TestRevert(t provider.T) {
+	old := s.personBuilder.WithID(1).
+		WithName("Old").
+		WithAge(20).
+		WithAddress("Address").
+		WithWork("Work").
+		WithVersion(1).
+		Build()
+	current := old
+	current.Name, current.Version = "New", 2
+	reverted := old
+	reverted.Version = 3
+
+	s.personRepoMock.On("GetVersion", mock.Anything, old.ID, 1).Return(&old, nil)
+	s.personRepoMock.On("GetVersion", mock.Anything, old.ID, 5).Return(nil, models.ErrNotFound)
+	s.personRepoMock.On("Get", mock.Anything, old.ID).Return(&current, nil)
+	s.personRepoMock.On("Update", mock.Anything, mock.MatchedBy(func(patch *models.PersonPatch) bool {
+		return patch.ID == old.ID && *patch.Name == old.Name && patch.Version == 2
+	})).Return(&reverted, nil)
+
+	cases := map[string]struct {
+		Version int
+		Person  *models.Person
+		Error   error
+	}{
+		"success": {
+			Version: 1,
+			Person:  &reverted,
+			Error:   nil,
+		},
+		"unknown version": {
+			Version: 5,
+			Person:  nil,
+			Error:   models.ErrNotFound,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t provider.T) {
+			resPerson, err := s.uc.Revert(context.Background(), old.ID, test.Version, 2)
+			t.Assert().ErrorIs(err, test.Error)
+			t.Assert().Equal(test.Person, resPerson)
+		})
+	}
+}
+
+func (s *PersonTestSuite) TestReplacePerson(t provider.T) {
+	person := s.personBuilder.WithID(1).
+		WithName("Name").
+		WithAge(20).
+		Build()
+	badID := s.personBuilder.WithID(0).Build()
+
+	s.personRepoMock.On("Replace", mock.Anything, &person, true).Return(true, nil)
+
+	created, err := s.uc.Replace(context.Background(), &person, true)
+	t.Assert().NoError(err)
+	t.Assert().True(created)
+
+	_, err = s.uc.Replace(context.Background(), &badID, true)
+	var errs validator.Errors
+	t.Assert().ErrorAs(err, &errs)
+}
+
 func (s *PersonTestSuite) TestGetPerson(t provider.T) {
 	person := s.personBuilder.WithID(1).
 		WithName("Name").
@@ -89,8 +210,8 @@ func (s *PersonTestSuite) TestGetPerson(t provider.T) {
 		WithWork("Work").
 		Build()
 
-	s.personRepoMock.On("Get", person.ID).Return(&person, nil)
-	result, err := s.uc.Get(person.ID)
+	s.personRepoMock.On("Get", mock.Anything, person.ID).Return(&person, nil)
+	result, err := s.uc.Get(context.Background(), person.ID)
 
 	t.Assert().NoError(err)
 	t.Assert().Equal(&person, result)
@@ -106,10 +227,10 @@ func (s *PersonTestSuite) TestDeletePerson(t provider.T) {
 		Build()
 	notFoundPerson := s.personBuilder.WithID(0).Build()
 
-	s.personRepoMock.On("Get", person.ID).Return(&person, nil)
-	s.personRepoMock.On("Delete", person.ID).Return(nil)
-	s.personRepoMock.On("Get", notFoundPerson.ID).Return(&notFoundPerson, errors.Wrap(err, "Person not found"))
-	s.personRepoMock.On("Delete", notFoundPerson.ID).Return(errors.Wrap(err, "Person not found"))
+	s.personRepoMock.On("Get", mock.Anything, person.ID).Return(&person, nil)
+	s.personRepoMock.On("Delete", mock.Anything, person.ID, 0).Return(nil)
+	s.personRepoMock.On("Get", mock.Anything, notFoundPerson.ID).Return(&notFoundPerson, errors.Wrap(err, "Person not found"))
+	s.personRepoMock.On("Delete", mock.Anything, notFoundPerson.ID, 0).Return(errors.Wrap(err, "Person not found"))
 
 	cases := map[string]struct {
 		PersonID int
@@ -127,7 +248,7 @@ func (s *PersonTestSuite) TestDeletePerson(t provider.T) {
 
 	for name, test := range cases {
 		t.Run(name, func(t provider.T) {
-			err := s.uc.Delete(test.PersonID)
+			err := s.uc.Delete(context.Background(), test.PersonID, 0)
 			t.Assert().ErrorIs(err, test.Error)
 		})
 	}
@@ -143,23 +264,228 @@ func (s *PersonTestSuite) TestGetAll(t provider.T) {
 		personsPtr[i] = &person
 	}
 
-	s.personRepoMock.On("GetAll").Return(personsPtr, nil)
+	page := &models.PersonPage{Persons: personsPtr, Total: int64(len(personsPtr))}
+
+	s.personRepoMock.On("GetAll", mock.Anything, models.PersonQuery{Limit: models.DefaultPageLimit}).Return(page, nil)
+	s.personRepoMock.On("GetAll", mock.Anything, models.PersonQuery{Limit: models.MaxPageLimit, Offset: 10}).Return(page, nil)
 
 	cases := map[string]struct {
-		Persons []models.Person
-		Error   error
+		Query models.PersonQuery
+		Error error
 	}{
-		"success": {
-			Persons: persons,
-			Error:   nil,
+		"default limit": {
+			Query: models.PersonQuery{},
+			Error: nil,
+		},
+		"limit too big": {
+			Query: models.PersonQuery{Limit: models.MaxPageLimit + 1, Offset: 10},
+			Error: nil,
 		},
 	}
 
 	for name, test := range cases {
 		t.Run(name, func(t provider.T) {
-			resPersons, err := s.uc.GetAll()
+			resPage, err := s.uc.GetAll(context.Background(), test.Query)
 			t.Assert().ErrorIs(err, test.Error)
-			t.Assert().Equal(personsPtr, resPersons)
+			t.Assert().Equal(page, resPage)
+		})
+	}
+}
+
+func (s *PersonTestSuite) TestEachPage(t provider.T) {
+	person := &models.Person{ID: 1, Name: "Name"}
+	s.personRepoMock.On("EachPage", mock.Anything, models.PersonQuery{Limit: models.MaxPageLimit}, mock.Anything, mock.Anything).
+		Return(func(_ context.Context, _ models.PersonQuery, start func(*models.PersonPage) error, fn func(*models.Person) error) error {
+			if err := start(&models.PersonPage{Total: 1}); err != nil {
+				return err
+			}
+			return fn(person)
 		})
+
+	var (
+		page    *models.PersonPage
+		persons []*models.Person
+	)
+	err := s.uc.EachPage(context.Background(), models.PersonQuery{Limit: models.MaxPageLimit + 1}, func(p *models.PersonPage) error {
+		page = p
+		return nil
+	}, func(p *models.Person) error {
+		persons = append(persons, p)
+		return nil
+	})
+	t.Require().NoError(err)
+	t.Assert().Equal(int64(1), page.Total)
+	t.Assert().Equal([]*models.Person{person}, persons)
+}
+
+func (s *PersonTestSuite) TestHistory(t provider.T) {
+	page := &models.AuditPage{
+		Entries: []*models.AuditEntry{{ID: 1, PersonID: 1, Version: 1, Operation: models.AuditCreate}},
+		Total:   1,
 	}
+
+	s.personRepoMock.On("History", mock.Anything, models.AuditQuery{PersonID: 1, Limit: models.DefaultPageLimit}).Return(page, nil)
+	s.personRepoMock.On("History", mock.Anything, models.AuditQuery{PersonID: 2, Limit: models.DefaultPageLimit}).Return(&models.AuditPage{}, nil)
+
+	cases := map[string]struct {
+		PersonID int
+		Page     *models.AuditPage
+		Error    error
+	}{
+		"found": {
+			PersonID: 1,
+			Page:     page,
+			Error:    nil,
+		},
+		"never existed": {
+			PersonID: 2,
+			Page:     nil,
+			Error:    models.ErrNotFound,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t provider.T) {
+			resPage, err := s.uc.History(context.Background(), models.AuditQuery{PersonID: test.PersonID})
+			t.Assert().ErrorIs(err, test.Error)
+			t.Assert().Equal(test.Page, resPage)
+		})
+	}
+}
+
+func (s *PersonTestSuite) TestBatch(t provider.T) {
+	valid := models.BatchOperation{Op: models.BatchDelete, ID: 1}
+	invalid := models.BatchOperation{Op: models.BatchCreate, Person: &models.Person{}}
+
+	s.personRepoMock.On("Batch", mock.Anything, []models.BatchOperation{valid}, false).
+		Return([]models.BatchOutcome{{}}, nil).Once()
+
+	cases := map[string]struct {
+		Batch    models.Batch
+		Outcomes []error
+	}{
+		"atomic with an invalid operation": {
+			Batch:    models.Batch{Atomic: true, Operations: []models.BatchOperation{valid, invalid}},
+			Outcomes: []error{models.ErrBatchAborted, validator.Errors{"name": "is required"}},
+		},
+		"best effort with an invalid operation": {
+			Batch:    models.Batch{Atomic: false, Operations: []models.BatchOperation{valid, invalid}},
+			Outcomes: []error{nil, validator.Errors{"name": "is required"}},
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t provider.T) {
+			outcomes, err := s.uc.Batch(context.Background(), &test.Batch)
+			t.Require().NoError(err)
+			t.Require().Len(outcomes, len(test.Outcomes))
+			for i, expected := range test.Outcomes {
+				t.Assert().Equal(expected, outcomes[i].Err)
+			}
+		})
+	}
+
+	_, err := s.uc.Batch(context.Background(), &models.Batch{})
+	t.Assert().ErrorAs(err, new(validator.Errors))
+}
+
+// rows returns a reader of the given import rows.
+func rows(rs ...*models.ImportRow) func() (*models.ImportRow, error) {
+	return func() (*models.ImportRow, error) {
+		if len(rs) == 0 {
+			return nil, io.EOF
+		}
+		row := rs[0]
+		rs = rs[1:]
+		return row, nil
+	}
+}
+
+func (s *PersonTestSuite) TestImport(t provider.T) {
+	newRows := func() []*models.ImportRow {
+		return []*models.ImportRow{
+			{Line: 2, Person: &models.Person{Name: "Ann", Age: 30}},
+			{Line: 3, Person: &models.Person{Age: 20}},
+			{Line: 4, Errors: validator.Errors{"row": "malformed JSON"}},
+			{Line: 5, Person: &models.Person{Name: "Bob", Age: 40}},
+		}
+	}
+
+	report, err := s.uc.Import(context.Background(), rows(newRows()...), true)
+	t.Require().NoError(err)
+	t.Assert().Equal(4, report.Rows)
+	t.Assert().Equal(2, report.Imported)
+	t.Assert().Equal(2, report.Rejected)
+	t.Assert().Equal([]models.ImportRejection{
+		{Line: 3, Errors: validator.Errors{"name": "is required"}},
+		{Line: 4, Errors: validator.Errors{"row": "malformed JSON"}},
+	}, report.Rejections)
+	t.Assert().Len(report.Preview, 2)
+
+	s.personRepoMock.On("Batch", mock.Anything, mock.MatchedBy(func(ops []models.BatchOperation) bool {
+		return len(ops) == 2 && ops[0].Person.Name == "Ann" && ops[1].Person.Name == "Bob"
+	}), false).Return([]models.BatchOutcome{
+		{Person: &models.Person{ID: 1, Name: "Ann", Age: 30, Version: 1}},
+		{Err: errors.Wrap(models.ErrConflict, "duplicate")},
+	}, nil).Once()
+
+	report, err = s.uc.Import(context.Background(), rows(newRows()...), false)
+	t.Require().NoError(err)
+	t.Assert().Equal(1, report.Imported)
+	t.Assert().Equal(3, report.Rejected)
+	t.Assert().Equal(5, report.Rejections[2].Line)
+	t.Assert().Empty(report.Preview)
+
+	_, err = s.uc.Import(context.Background(), func() (*models.ImportRow, error) {
+		return nil, models.ErrValidation
+	}, false)
+	t.Assert().ErrorIs(err, models.ErrValidation)
+}
+
+func (s *PersonTestSuite) TestImportStopped(t provider.T) {
+	ann := &models.ImportRow{Line: 2, Person: &models.Person{Name: "Ann", Age: 30}}
+	bob := &models.ImportRow{Line: 3, Person: &models.Person{Name: "Bob", Age: 40}}
+	broken := func(rs ...*models.ImportRow) func() (*models.ImportRow, error) {
+		next := rows(rs...)
+		return func() (*models.ImportRow, error) {
+			row, err := next()
+			if errors.Is(err, io.EOF) {
+				return &models.ImportRow{Line: 4}, models.ErrValidation
+			}
+			return row, err
+		}
+	}
+
+	s.personRepoMock.On("Batch", mock.Anything, mock.MatchedBy(func(ops []models.BatchOperation) bool {
+		return len(ops) == 2
+	}), false).Return([]models.BatchOutcome{{Person: ann.Person}, {Person: bob.Person}}, nil).Once()
+
+	report, err := s.uc.Import(context.Background(), broken(ann, bob), false)
+	t.Assert().ErrorIs(err, models.ErrValidation)
+	t.Require().NotNil(report)
+	t.Assert().Equal(2, report.Imported)
+	t.Assert().Equal(&models.ImportStop{Line: 4}, report.Stopped)
+
+	s.personRepoMock.On("Batch", mock.Anything, mock.MatchedBy(func(ops []models.BatchOperation) bool {
+		return len(ops) == 1
+	}), false).Return(nil, models.ErrUnavailable).Once()
+
+	report, err = s.uc.Import(context.Background(), rows(ann), false)
+	t.Assert().ErrorIs(err, models.ErrUnavailable)
+	t.Require().NotNil(report)
+	t.Assert().Equal(0, report.Imported)
+	t.Assert().Equal(&models.ImportStop{Line: 2}, report.Stopped)
+}
+
+func (s *PersonTestSuite) TestPurger(t provider.T) {
+	retention := time.Hour
+	s.personRepoMock.On("Purge", mock.Anything, mock.MatchedBy(func(before time.Time) bool {
+		return time.Since(before) >= retention
+	})).Return(int64(2), nil).Once()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	purger := &Purger{UseCase: s.uc, Retention: retention, Interval: time.Minute, Logger: zap.NewNop().Sugar()}
+	purger.Run(ctx)
 }
@@ -37,6 +37,11 @@ func (b *PersonBuilder) WithWork(work string) *PersonBuilder {
 	return b
 }
 
+func (b *PersonBuilder) WithVersion(version int) *PersonBuilder {
+	b.person.Version = version
+	return b
+}
+
 func (b *PersonBuilder) Build() models.Person {
 	return b.person
 }
@@ -1,5 +1,3 @@
-\connect persons program
-
 CREATE TABLE IF NOT EXISTS people
 (
     id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
@@ -7,4 +5,4 @@ CREATE TABLE IF NOT EXISTS people
     age INT NOT NULL,
     address VARCHAR(1000) NOT NULL,
     work VARCHAR(1000) NOT NULL
-);
\ No newline at end of file
+);
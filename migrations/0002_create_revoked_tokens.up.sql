@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS revoked_tokens
+(
+    jti VARCHAR(64) PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS revoked_tokens_expires_at_idx ON revoked_tokens (expires_at);
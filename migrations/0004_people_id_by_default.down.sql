@@ -0,0 +1,2 @@
+ALTER TABLE people
+    ALTER COLUMN id SET GENERATED ALWAYS;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS person_audit;
+
+DROP FUNCTION IF EXISTS person_audit_append_only();
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS person_audit
+(
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    person_id INT NOT NULL,
+    version INT NOT NULL,
+    operation VARCHAR(16) NOT NULL,
+    actor_id INT,
+    request_id VARCHAR(128) NOT NULL DEFAULT '',
+    changes JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS person_audit_person_id_idx ON person_audit (person_id, id);
+
+CREATE OR REPLACE FUNCTION person_audit_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'person_audit is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS person_audit_append_only ON person_audit;
+CREATE TRIGGER person_audit_append_only
+    BEFORE UPDATE OR DELETE ON person_audit
+    FOR EACH ROW EXECUTE FUNCTION person_audit_append_only();
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS person_versions
+(
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    person_id INT NOT NULL,
+    version INT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    age INT NOT NULL,
+    address VARCHAR(1000) NOT NULL,
+    work VARCHAR(1000) NOT NULL,
+    deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS person_versions_person_id_idx ON person_versions (person_id, created_at);
+
+-- Existing persons start their history with their current state, which is
+-- all that is known of their past, so as_of reads before this migration
+-- still find them. The earliest time Go can represent stands in for
+-- -infinity, which the drivers cannot scan into a time. Trashed persons are
+-- also recorded as deleted from the time they were.
+INSERT INTO person_versions (person_id, version, name, age, address, work, deleted, created_at)
+SELECT id, version, name, age, address, work, FALSE, TIMESTAMPTZ '0001-01-01 00:00:00+00'
+FROM people;
+
+INSERT INTO person_versions (person_id, version, name, age, address, work, deleted, created_at)
+SELECT id, version, name, age, address, work, TRUE, deleted_at
+FROM people
+WHERE deleted_at IS NOT NULL;
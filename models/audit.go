@@ -0,0 +1,111 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Operations recorded in the audit trail.
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditReplace = "replace"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	AuditPurge   = "purge"
+)
+
+// FieldChange holds the JSON values of a field before and after a change.
+// A side is null when the person did not exist on it.
+type FieldChange struct {
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+// AuditChanges maps a field name to its change. It is stored as JSON.
+type AuditChanges map[string]FieldChange
+
+func (c AuditChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return "{}", nil
+	}
+
+	data, err := json.Marshal(c)
+	return string(data), err
+}
+
+func (c *AuditChanges) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*c = AuditChanges{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	}
+
+	return errors.Errorf("can`t scan %T into AuditChanges", src)
+}
+
+// AuditEntry is one change of a person in the append-only audit trail.
+type AuditEntry struct {
+	ID       int64 `json:"id" db:"id"`
+	PersonID int   `json:"person_id" db:"person_id"`
+	// Version is the version of the person after the change.
+	Version   int    `json:"version" db:"version"`
+	Operation string `json:"operation" db:"operation"`
+	// ActorID is the user that made the change, nil for the service itself
+	// or when auth is disabled.
+	ActorID   *int         `json:"actor_id" db:"actor_id"`
+	RequestID string       `json:"request_id,omitempty" db:"request_id"`
+	Changes   AuditChanges `json:"changes" db:"changes"`
+	CreatedAt time.Time    `json:"created_at" db:"created_at"`
+}
+
+func (AuditEntry) TableName() string {
+	return "person_audit"
+}
+
+type AuditQuery struct {
+	PersonID int
+	Limit    int
+	Offset   int
+}
+
+// AuditPage holds entries newest first.
+type AuditPage struct {
+	Entries []*AuditEntry
+	Total   int64
+}
+
+// Diff lists the fields that differ between before and after. Either may be
+// nil for a person that does not exist on that side.
+func Diff(before, after *Person) AuditChanges {
+	changes := AuditChanges{}
+	field := func(name string, value func(p *Person) interface{}) {
+		b, a := fieldJSON(before, value), fieldJSON(after, value)
+		if string(b) != string(a) {
+			changes[name] = FieldChange{Before: b, After: a}
+		}
+	}
+
+	field("name", func(p *Person) interface{} { return p.Name })
+	field("age", func(p *Person) interface{} { return p.Age })
+	field("address", func(p *Person) interface{} { return p.Address })
+	field("work", func(p *Person) interface{} { return p.Work })
+
+	return changes
+}
+
+func fieldJSON(p *Person, value func(p *Person) interface{}) json.RawMessage {
+	if p == nil {
+		return json.RawMessage("null")
+	}
+
+	data, _ := json.Marshal(value(p))
+	return data
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/Davmie/person_service/pkg/validator"
+)
+
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+
+	MaxBatchOperations = 1000
+)
+
+// Batch is a list of operations on persons. An atomic batch applies all of
+// them or none; otherwise each operation succeeds or fails on its own.
+type Batch struct {
+	Atomic     bool
+	Operations []BatchOperation
+}
+
+// BatchOperation is one operation of a batch. A create writes Person, an
+// update Patch and a delete removes ID. A non-zero Version of the update
+// patch or the delete must match the stored one.
+type BatchOperation struct {
+	Op      string
+	Person  *Person
+	Patch   *PersonPatch
+	ID      int
+	Version int
+}
+
+// BatchOutcome is the result of one operation: the stored person, nil after
+// a delete, or the error that stopped it.
+type BatchOutcome struct {
+	Person *Person
+	Err    error
+}
+
+// Validate normalizes the operation and checks it. The ID and version of a
+// person to create are assigned by the repository.
+func (op *BatchOperation) Validate() validator.Errors {
+	switch op.Op {
+	case BatchCreate:
+		if op.Person == nil {
+			return validator.Errors{"data": "is required"}
+		}
+		op.Person.ID, op.Person.Version = 0, 0
+		return op.Person.Validate()
+	case BatchUpdate:
+		if op.Patch == nil {
+			return validator.Errors{"data": "is required"}
+		}
+		if op.Patch.ID <= 0 {
+			return validator.Errors{"id": "must be positive"}
+		}
+		return op.Patch.Validate()
+	case BatchDelete:
+		if op.ID <= 0 {
+			return validator.Errors{"id": "must be positive"}
+		}
+		return nil
+	}
+
+	return validator.Errors{"op": fmt.Sprintf("unknown operation %q", op.Op)}
+}
@@ -0,0 +1,23 @@
+package models
+
+import "github.com/pkg/errors"
+
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrValidation   = errors.New("invalid data")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrUnavailable  = errors.New("service unavailable")
+	// ErrPreconditionFailed means the record changed since the version the
+	// caller based its write on.
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrPreconditionRequired means a conditional write was required but
+	// the caller sent no version.
+	ErrPreconditionRequired = errors.New("precondition required")
+	// ErrPatchTestFailed means a test operation of a JSON Patch did not
+	// match the record.
+	ErrPatchTestFailed = errors.New("patch test failed")
+	// ErrBatchAborted means an operation of an atomic batch was not applied
+	// because another one failed.
+	ErrBatchAborted = errors.New("batch aborted")
+)
@@ -0,0 +1,51 @@
+package models
+
+import "github.com/Davmie/person_service/pkg/validator"
+
+const (
+	// MaxImportRejections caps the rejected rows listed in a report; the
+	// count covers all of them.
+	MaxImportRejections = 1000
+	// ImportPreviewSize is the number of persons a dry run shows.
+	ImportPreviewSize = 10
+)
+
+// ImportRow is one row of an import file: the person it holds, or the errors
+// that kept it from being decoded. Line is where the row starts.
+type ImportRow struct {
+	Line   int
+	Person *Person
+	Errors validator.Errors
+}
+
+type ImportRejection struct {
+	Line   int              `json:"line"`
+	Errors validator.Errors `json:"errors"`
+}
+
+type ImportReport struct {
+	DryRun bool `json:"dry_run"`
+	Rows   int  `json:"rows"`
+	// Imported counts the persons written, or that would be on a dry run.
+	Imported   int               `json:"imported"`
+	Rejected   int               `json:"rejected"`
+	Rejections []ImportRejection `json:"rejections"`
+	// Preview holds the first persons of a dry run as they would be stored.
+	Preview []*Person `json:"preview,omitempty"`
+	// Stopped is set when the import ended before the end of the file.
+	Stopped *ImportStop `json:"stopped,omitempty"`
+}
+
+// ImportStop tells where an import ended early. The rows before Line were
+// processed as reported, none from Line on. Line is 0 if it is unknown.
+type ImportStop struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+func (r *ImportReport) Reject(line int, errs validator.Errors) {
+	r.Rejected++
+	if len(r.Rejections) < MaxImportRejections {
+		r.Rejections = append(r.Rejections, ImportRejection{Line: line, Errors: errs})
+	}
+}
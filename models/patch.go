@@ -0,0 +1,252 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/Davmie/person_service/pkg/validator"
+	"github.com/pkg/errors"
+)
+
+// PersonPatch is a partial update of a person. A nil field is left
+// unchanged, a set one is written even if it holds the zero value.
+type PersonPatch struct {
+	ID int
+	// Version is the version the patch applies to, 0 means any.
+	Version int
+	Name    *string
+	Age     *int
+	Address *string
+	Work    *string
+}
+
+// Patch returns a patch that writes every field of p, as a full
+// replacement does.
+func (p *Person) Patch() *PersonPatch {
+	return &PersonPatch{
+		ID:      p.ID,
+		Version: p.Version,
+		Name:    &p.Name,
+		Age:     &p.Age,
+		Address: &p.Address,
+		Work:    &p.Work,
+	}
+}
+
+// UnmarshalJSON reads an RFC 7396 JSON Merge Patch. Absent members are left
+// unchanged and null resets a field to its zero value. Members that are not
+// writable, like id and version, are ignored.
+func (p *PersonPatch) UnmarshalJSON(data []byte) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	if members == nil {
+		return errors.New("merge patch must be a JSON object")
+	}
+
+	fields := []struct {
+		name string
+		dst  interface{}
+	}{
+		{"name", &p.Name},
+		{"age", &p.Age},
+		{"address", &p.Address},
+		{"work", &p.Work},
+	}
+	for _, f := range fields {
+		raw, ok := members[f.name]
+		if !ok {
+			continue
+		}
+		if err := decodeMember(raw, f.dst); err != nil {
+			return errors.Wrapf(err, "bad %s", f.name)
+		}
+	}
+
+	return nil
+}
+
+// decodeMember sets the pointer dst points to, mapping null to a pointer to
+// the zero value instead of nil.
+func decodeMember(raw json.RawMessage, dst interface{}) error {
+	ptr := reflect.ValueOf(dst).Elem()
+	value := reflect.New(ptr.Type().Elem())
+	if !bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		if err := json.Unmarshal(raw, value.Interface()); err != nil {
+			return err
+		}
+	}
+	ptr.Set(value)
+
+	return nil
+}
+
+// Validate normalizes the set fields of p and checks them. The name cannot
+// be cleared.
+func (p *PersonPatch) Validate() validator.Errors {
+	for _, s := range []*string{p.Name, p.Address, p.Work} {
+		if s != nil {
+			*s = strings.TrimSpace(*s)
+		}
+	}
+
+	v := validator.New()
+	if p.Name != nil {
+		v.Required("name", *p.Name)
+		v.MaxLen("name", *p.Name, MaxNameLen)
+	}
+	if p.Age != nil {
+		v.Range("age", *p.Age, MinAge, MaxAge)
+	}
+	if p.Address != nil {
+		v.MaxLen("address", *p.Address, MaxAddressLen)
+	}
+	if p.Work != nil {
+		v.MaxLen("work", *p.Work, MaxWorkLen)
+	}
+
+	return v.Errors()
+}
+
+// Apply writes the set fields of p to person.
+func (p *PersonPatch) Apply(person *Person) {
+	if p.Name != nil {
+		person.Name = *p.Name
+	}
+	if p.Age != nil {
+		person.Age = *p.Age
+	}
+	if p.Address != nil {
+		person.Address = *p.Address
+	}
+	if p.Work != nil {
+		person.Work = *p.Work
+	}
+}
+
+// PatchOperation is one operation of an RFC 6902 JSON Patch.
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// JSONPatch is an RFC 6902 JSON Patch of a person. Paths address the
+// writable members only, so add and replace are the same and remove resets
+// a member to its zero value.
+type JSONPatch []PatchOperation
+
+// Apply evaluates ops in order against person and returns the members they
+// changed as a PersonPatch. A failed test operation yields
+// ErrPatchTestFailed, a malformed operation validator.Errors keyed by its
+// index.
+func (ops JSONPatch) Apply(person *Person) (*PersonPatch, error) {
+	current, err := json.Marshal(person)
+	if err != nil {
+		return nil, errors.Wrap(err, "can`t marshal person")
+	}
+	var doc map[string]json.RawMessage
+	if err = json.Unmarshal(current, &doc); err != nil {
+		return nil, errors.Wrap(err, "can`t unmarshal person")
+	}
+
+	changed := map[string]json.RawMessage{}
+	for i, op := range ops {
+		field := fmt.Sprintf("operations[%d]", i)
+
+		path, ok := patchMember(op.Path)
+		if !ok {
+			return nil, validator.Errors{field: fmt.Sprintf("unknown path %q", op.Path)}
+		}
+
+		switch op.Op {
+		case "add", "replace", "test":
+			if op.Value == nil {
+				return nil, validator.Errors{field: "value is required"}
+			}
+		case "move", "copy":
+			if _, ok = patchMember(op.From); !ok {
+				return nil, validator.Errors{field: fmt.Sprintf("unknown from %q", op.From)}
+			}
+		}
+
+		switch op.Op {
+		case "add", "replace":
+			changed[path] = op.Value
+		case "remove":
+			changed[path] = json.RawMessage("null")
+		case "move":
+			from, _ := patchMember(op.From)
+			changed[path] = doc[from]
+			// Moving a member onto itself changes nothing (RFC 6902 4.4).
+			if from != path {
+				changed[from] = json.RawMessage("null")
+			}
+		case "copy":
+			from, _ := patchMember(op.From)
+			changed[path] = doc[from]
+		case "test":
+			if !jsonEqual(doc[path], op.Value) {
+				return nil, errors.Wrapf(ErrPatchTestFailed, "operations[%d]", i)
+			}
+			continue
+		default:
+			return nil, validator.Errors{field: fmt.Sprintf("unknown op %q", op.Op)}
+		}
+
+		// Later operations see the result of earlier ones.
+		for member, value := range changed {
+			doc[member] = normalizeNull(member, value)
+		}
+	}
+
+	data, err := json.Marshal(changed)
+	if err != nil {
+		return nil, errors.Wrap(err, "can`t marshal patch")
+	}
+	patch := &PersonPatch{ID: person.ID}
+	if err = json.Unmarshal(data, patch); err != nil {
+		return nil, errors.Wrap(ErrValidation, err.Error())
+	}
+
+	return patch, nil
+}
+
+var patchMembers = map[string]string{
+	"/name":    "name",
+	"/age":     "age",
+	"/address": "address",
+	"/work":    "work",
+}
+
+func patchMember(path string) (string, bool) {
+	member, ok := patchMembers[path]
+	return member, ok
+}
+
+// normalizeNull replaces null with the zero value the member is reset to,
+// so that test operations compare against what would be stored.
+func normalizeNull(member string, value json.RawMessage) json.RawMessage {
+	if !bytes.Equal(bytes.TrimSpace(value), []byte("null")) {
+		return value
+	}
+	if member == "age" {
+		return json.RawMessage("0")
+	}
+
+	return json.RawMessage(`""`)
+}
+
+func jsonEqual(a, b json.RawMessage) bool {
+	var x, y interface{}
+	if json.Unmarshal(a, &x) != nil || json.Unmarshal(b, &y) != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(x, y)
+}
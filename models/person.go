@@ -1,9 +1,100 @@
 package models
 
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Davmie/person_service/pkg/validator"
+	"gorm.io/gorm"
+)
+
+const (
+	MaxNameLen    = 255
+	MaxAddressLen = 1000
+	MaxWorkLen    = 1000
+	MinAge        = 0
+	MaxAge        = 150
+)
+
 type Person struct {
 	ID      int    `json:"id" db:"id"`
 	Name    string `json:"name" db:"name"`
 	Age     int    `json:"age" db:"age"`
 	Address string `json:"address" db:"address"`
 	Work    string `json:"work" db:"work"`
+	// Version is incremented on every write and backs the ETag of the
+	// person. As input to Update and Delete a non-zero Version is the
+	// version the caller expects to change, or AnyVersion or NoVersion.
+	Version int `json:"version" db:"version" gorm:"default:1"`
+	// DeletedAt is set while the person is in the trash. Reads through gorm
+	// skip such rows unless they are unscoped.
+	DeletedAt gorm.DeletedAt `json:"-" db:"deleted_at" faker:"-"`
+}
+
+// TrashedPerson is a soft-deleted person as listed in the trash.
+type TrashedPerson struct {
+	*Person
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+func NewTrashedPerson(p *Person) *TrashedPerson {
+	return &TrashedPerson{Person: p, DeletedAt: p.DeletedAt.Time}
+}
+
+// Expected versions of a write besides an exact one; zero expects nothing.
+const (
+	// AnyVersion expects the person to exist, like If-Match: *.
+	AnyVersion = -1
+	// NoVersion expects the person not to exist, like If-None-Match: *.
+	NoVersion = -2
+)
+
+// MatchesVersion reports whether a stored person at version satisfies the
+// expected version of a write.
+func MatchesVersion(expected, version int) bool {
+	switch expected {
+	case 0, AnyVersion:
+		return true
+	case NoVersion:
+		return false
+	}
+
+	return expected == version
+}
+
+// ExpectsExisting reports whether a write with the expected version fails
+// with ErrPreconditionFailed instead of ErrNotFound when the person is
+// missing.
+func ExpectsExisting(expected int) bool {
+	return expected > 0 || expected == AnyVersion
+}
+
+// ETag is the strong entity tag of the person's current version.
+func (p *Person) ETag() string {
+	return `"` + strconv.Itoa(p.Version) + `"`
+}
+
+func (p *Person) Normalize() {
+	p.Name = strings.TrimSpace(p.Name)
+	p.Address = strings.TrimSpace(p.Address)
+	p.Work = strings.TrimSpace(p.Work)
+}
+
+// Validate normalizes p and checks it as a complete record.
+func (p *Person) Validate() validator.Errors {
+	p.Normalize()
+
+	v := validator.New()
+	v.Required("name", p.Name)
+	p.validateFields(v)
+
+	return v.Errors()
+}
+
+func (p *Person) validateFields(v *validator.Validator) {
+	v.MaxLen("name", p.Name, MaxNameLen)
+	v.Range("age", p.Age, MinAge, MaxAge)
+	v.MaxLen("address", p.Address, MaxAddressLen)
+	v.MaxLen("work", p.Work, MaxWorkLen)
 }
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Davmie/person_service/pkg/validator"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+)
+
+type PersonValidationTestSuite struct {
+	suite.Suite
+}
+
+func TestPersonValidationSuite(t *testing.T) {
+	suite.RunSuite(t, new(PersonValidationTestSuite))
+}
+
+func (s *PersonValidationTestSuite) TestValidate(t provider.T) {
+	cases := map[string]struct {
+		Person Person
+		Fields []string
+	}{
+		"success": {
+			Person: Person{Name: "Name", Age: 20, Address: "Address", Work: "Work"},
+		},
+		"empty name": {
+			Person: Person{Name: "   ", Age: 20},
+			Fields: []string{"name"},
+		},
+		"negative age": {
+			Person: Person{Name: "Name", Age: -1},
+			Fields: []string{"age"},
+		},
+		"too long": {
+			Person: Person{Name: strings.Repeat("n", MaxNameLen+1), Address: strings.Repeat("a", MaxAddressLen+1)},
+			Fields: []string{"name", "address"},
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t provider.T) {
+			errs := test.Person.Validate()
+			t.Assert().Len(errs, len(test.Fields))
+			for _, field := range test.Fields {
+				t.Assert().Contains(errs, field)
+			}
+		})
+	}
+}
+
+func (s *PersonValidationTestSuite) TestMergePatch(t provider.T) {
+	var patch PersonPatch
+	err := json.Unmarshal([]byte(`{"age": 0, "work": null, "address": "  Address  ", "id": 5}`), &patch)
+	t.Require().NoError(err)
+
+	t.Assert().Nil(patch.Validate())
+	t.Assert().Nil(patch.Name)
+	t.Assert().Equal(0, *patch.Age)
+	t.Assert().Equal("", *patch.Work)
+	t.Assert().Equal("Address", *patch.Address)
+	t.Assert().Equal(0, patch.ID)
+
+	err = json.Unmarshal([]byte(`{"name": null}`), &patch)
+	t.Require().NoError(err)
+	t.Assert().Contains(patch.Validate(), "name")
+
+	err = json.Unmarshal([]byte(`[]`), &patch)
+	t.Assert().Error(err)
+}
+
+func (s *PersonValidationTestSuite) TestJSONPatch(t provider.T) {
+	person := &Person{ID: 1, Name: "Name", Age: 20, Address: "Address", Work: "Work"}
+
+	patch, err := JSONPatch{
+		{Op: "test", Path: "/age", Value: []byte(`20`)},
+		{Op: "move", From: "/work", Path: "/address"},
+		{Op: "test", Path: "/work", Value: []byte(`""`)},
+		{Op: "replace", Path: "/age", Value: []byte(`0`)},
+	}.Apply(person)
+	t.Require().NoError(err)
+
+	t.Assert().Equal(1, patch.ID)
+	t.Assert().Nil(patch.Name)
+	t.Assert().Equal(0, *patch.Age)
+	t.Assert().Equal("Work", *patch.Address)
+	t.Assert().Equal("", *patch.Work)
+
+	patch, err = JSONPatch{
+		{Op: "move", From: "/name", Path: "/name"},
+		{Op: "move", From: "/age", Path: "/age"},
+	}.Apply(person)
+	t.Require().NoError(err)
+	t.Assert().Equal("Name", *patch.Name)
+	t.Assert().Equal(20, *patch.Age)
+	t.Assert().Empty(patch.Validate())
+
+	_, err = JSONPatch{{Op: "test", Path: "/name", Value: []byte(`"Other"`)}}.Apply(person)
+	t.Assert().ErrorIs(err, ErrPatchTestFailed)
+
+	_, err = JSONPatch{{Op: "replace", Path: "/id", Value: []byte(`2`)}}.Apply(person)
+	var errs validator.Errors
+	t.Assert().ErrorAs(err, &errs)
+
+	_, err = JSONPatch{{Op: "replace", Path: "/age", Value: []byte(`"old"`)}}.Apply(person)
+	t.Assert().ErrorIs(err, ErrValidation)
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultPageLimit = 100
+	MaxPageLimit     = 1000
+)
+
+// PersonSortFields lists the columns persons can be sorted by.
+var PersonSortFields = map[string]bool{
+	"id":      true,
+	"name":    true,
+	"age":     true,
+	"address": true,
+	"work":    true,
+}
+
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+type PersonFilter struct {
+	AgeGte          *int
+	AgeLte          *int
+	Work            *string
+	AddressContains string
+	NamePrefix      string
+}
+
+type PersonQuery struct {
+	Limit  int
+	Offset int
+	Cursor *Cursor
+	Sort   []SortField
+	Filter PersonFilter
+	// Trashed lists the soft-deleted persons instead of the live ones.
+	Trashed bool
+}
+
+type PersonPage struct {
+	Persons []*Person
+	Total   int64
+	HasNext bool
+	HasPrev bool
+	// First and Last hold the sort keys of the page's bounding rows when the
+	// persons are streamed instead of loaded into Persons.
+	First *Person
+	Last  *Person
+}
+
+// Bounds returns the first and last person of the page, or nils for an
+// empty one.
+func (p *PersonPage) Bounds() (first, last *Person) {
+	if len(p.Persons) > 0 {
+		return p.Persons[0], p.Persons[len(p.Persons)-1]
+	}
+	return p.First, p.Last
+}
+
+// Cursor points at a row of a keyset-paginated listing. Key holds the values
+// of the sort columns of that row; Before selects the page preceding it.
+type Cursor struct {
+	Key    Person `json:"k"`
+	Sort   string `json:"s"`
+	Before bool   `json:"b,omitempty"`
+}
+
+// SortKey returns the sort in its query string form, always ending with
+// the id tie-breaker so that keyset pagination is stable.
+func SortKey(sort []SortField) string {
+	parts := make([]string, 0, len(sort)+1)
+	hasID := false
+	for _, f := range sort {
+		if f.Field == "id" {
+			hasID = true
+		}
+		if f.Desc {
+			parts = append(parts, "-"+f.Field)
+		} else {
+			parts = append(parts, f.Field)
+		}
+	}
+	if !hasID {
+		parts = append(parts, "id")
+	}
+
+	return strings.Join(parts, ",")
+}
+
+func NewCursor(p *Person, sort []SortField, before bool) *Cursor {
+	return &Cursor{Key: *p, Sort: SortKey(sort), Before: before}
+}
+
+func (c *Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.Wrap(err, "bad cursor encoding")
+	}
+
+	c := &Cursor{}
+	if err = json.Unmarshal(data, c); err != nil {
+		return nil, errors.Wrap(err, "bad cursor")
+	}
+
+	return c, nil
+}
+
+// Value returns the value of the sort column field in the cursor row.
+func (c *Cursor) Value(field string) interface{} {
+	switch field {
+	case "name":
+		return c.Key.Name
+	case "age":
+		return c.Key.Age
+	case "address":
+		return c.Key.Address
+	case "work":
+		return c.Key.Work
+	default:
+		return c.Key.ID
+	}
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// PersonSnapshot is a person as it was from CreatedAt until the next snapshot
+// of the same person. One is taken with every change; Deleted marks the
+// person as being in the trash from then on.
+type PersonSnapshot struct {
+	ID        int64     `db:"id"`
+	PersonID  int       `db:"person_id"`
+	Version   int       `db:"version"`
+	Name      string    `db:"name"`
+	Age       int       `db:"age"`
+	Address   string    `db:"address"`
+	Work      string    `db:"work"`
+	Deleted   bool      `db:"deleted"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (PersonSnapshot) TableName() string {
+	return "person_versions"
+}
+
+func NewPersonSnapshot(p *Person, deleted bool, at time.Time) *PersonSnapshot {
+	return &PersonSnapshot{
+		PersonID:  p.ID,
+		Version:   p.Version,
+		Name:      p.Name,
+		Age:       p.Age,
+		Address:   p.Address,
+		Work:      p.Work,
+		Deleted:   deleted,
+		CreatedAt: at,
+	}
+}
+
+func (s *PersonSnapshot) Person() *Person {
+	return &Person{
+		ID:      s.PersonID,
+		Name:    s.Name,
+		Age:     s.Age,
+		Address: s.Address,
+		Work:    s.Work,
+		Version: s.Version,
+	}
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Client is an API consumer allowed to obtain tokens with its secret.
+type Client struct {
+	ID         string
+	SecretHash string
+	UserID     int
+	Role       string
+}
+
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+type RevokedToken struct {
+	JTI       string    `db:"jti" gorm:"column:jti;primaryKey"`
+	ExpiresAt time.Time `db:"expires_at"`
+}
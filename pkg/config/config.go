@@ -0,0 +1,440 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	envPrefix = "PERSON_SERVICE_"
+	configKey = "config"
+
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+)
+
+type Config struct {
+	Storage  string         `yaml:"storage"`
+	Server   ServerConfig   `yaml:"server"`
+	Postgres PostgresConfig `yaml:"postgres"`
+	Migrate  MigrateConfig  `yaml:"migrate"`
+	Log      LogConfig      `yaml:"log"`
+	Auth     AuthConfig     `yaml:"auth"`
+	Tracing  TracingConfig  `yaml:"tracing"`
+	Trash    TrashConfig    `yaml:"trash"`
+}
+
+type ServerConfig struct {
+	Addr              string        `yaml:"addr"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	RequestTimeout    time.Duration `yaml:"request_timeout"`
+	// StreamTimeout replaces RequestTimeout, ReadTimeout and WriteTimeout
+	// for exports and imports, whose bodies are streamed.
+	StreamTimeout time.Duration `yaml:"stream_timeout"`
+	// ShutdownTimeout bounds how long in-flight requests may drain after a
+	// termination signal.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// DrainDelay is how long the server keeps accepting connections after it
+	// reported not ready, so that load balancers stop routing to it first.
+	DrainDelay time.Duration `yaml:"drain_delay"`
+	// RequireIfMatch makes PUT, PATCH and DELETE of a person fail with 428
+	// unless they carry an If-Match header.
+	RequireIfMatch bool `yaml:"require_if_match"`
+	// PutCreates lets PUT create a person at an ID that does not exist yet.
+	PutCreates bool `yaml:"put_creates"`
+}
+
+type PostgresConfig struct {
+	DSN string `yaml:"dsn"`
+}
+
+type MigrateConfig struct {
+	OnStart bool `yaml:"on_start"`
+}
+
+type LogConfig struct {
+	Level string `yaml:"level"`
+	// AccessSampleRate is the share of successful requests written to the
+	// access log; failed requests are always logged.
+	AccessSampleRate float64 `yaml:"access_sample_rate"`
+}
+
+// TracingConfig selects where spans go. Exporter is "none", "stdout",
+// "file" (JSON lines written to File) or "otlp" (OTLP over HTTP to
+// OTLPEndpoint).
+type TracingConfig struct {
+	Exporter     string  `yaml:"exporter"`
+	File         string  `yaml:"file"`
+	OTLPEndpoint string  `yaml:"otlp_endpoint"`
+	SampleRatio  float64 `yaml:"sample_ratio"`
+}
+
+type TrashConfig struct {
+	// Retention is how long deleted persons stay restorable; 0 keeps them
+	// forever.
+	Retention     time.Duration `yaml:"retention"`
+	PurgeInterval time.Duration `yaml:"purge_interval"`
+}
+
+type AuthConfig struct {
+	Enabled    bool          `yaml:"enabled"`
+	JWTSecret  string        `yaml:"jwt_secret"`
+	AccessTTL  time.Duration `yaml:"access_ttl"`
+	RefreshTTL time.Duration `yaml:"refresh_ttl"`
+	// Keys replace JWTSecret when set. The newest key whose not_before has
+	// passed signs tokens; older keys verify tokens for KeyGrace after their
+	// successor took over.
+	Keys              []KeyConfig   `yaml:"keys"`
+	KeyGrace          time.Duration `yaml:"key_grace"`
+	KeyReloadInterval time.Duration `yaml:"key_reload_interval"`
+	// Policies maps a route pattern to the roles allowed to call it.
+	Policies map[string][]string `yaml:"policies"`
+	Clients  []ClientConfig      `yaml:"clients"`
+}
+
+type KeyConfig struct {
+	ID        string    `yaml:"id"`
+	Algorithm string    `yaml:"algorithm"`
+	File      string    `yaml:"file"`
+	NotBefore time.Time `yaml:"not_before"`
+}
+
+// ClientConfig is a consumer allowed to exchange its secret for tokens.
+// SecretHash is a bcrypt hash of the secret.
+type ClientConfig struct {
+	ID         string `yaml:"id"`
+	SecretHash string `yaml:"secret_hash"`
+	UserID     int    `yaml:"user_id"`
+	Role       string `yaml:"role"`
+}
+
+// option describes a setting that can be overridden by an environment
+// variable (envPrefix + upper-cased key) and by a -key command line flag.
+type option struct {
+	key   string
+	usage string
+	set   func(*Config, string) error
+}
+
+var options = []option{
+	{"storage", "person storage backend (postgres, memory)", func(c *Config, v string) error {
+		c.Storage = v
+		return nil
+	}},
+	{"server_addr", "listen address", func(c *Config, v string) error {
+		c.Server.Addr = v
+		return nil
+	}},
+	{"server_read_timeout", "server read timeout", func(c *Config, v string) error {
+		return setDuration(&c.Server.ReadTimeout, v)
+	}},
+	{"server_read_header_timeout", "server read header timeout", func(c *Config, v string) error {
+		return setDuration(&c.Server.ReadHeaderTimeout, v)
+	}},
+	{"server_write_timeout", "server write timeout", func(c *Config, v string) error {
+		return setDuration(&c.Server.WriteTimeout, v)
+	}},
+	{"server_request_timeout", "deadline for handling a single request", func(c *Config, v string) error {
+		return setDuration(&c.Server.RequestTimeout, v)
+	}},
+	{"server_stream_timeout", "deadline for streamed exports and imports", func(c *Config, v string) error {
+		return setDuration(&c.Server.StreamTimeout, v)
+	}},
+	{"server_shutdown_timeout", "how long in-flight requests may drain on shutdown", func(c *Config, v string) error {
+		return setDuration(&c.Server.ShutdownTimeout, v)
+	}},
+	{"server_drain_delay", "how long to report not ready before draining on shutdown", func(c *Config, v string) error {
+		return setDuration(&c.Server.DrainDelay, v)
+	}},
+	{"server_require_if_match", "require If-Match on person writes", func(c *Config, v string) error {
+		require, err := strconv.ParseBool(v)
+		c.Server.RequireIfMatch = require
+		return err
+	}},
+	{"server_put_creates", "let PUT create persons at client-chosen IDs", func(c *Config, v string) error {
+		creates, err := strconv.ParseBool(v)
+		c.Server.PutCreates = creates
+		return err
+	}},
+	{"postgres_dsn", "postgres connection string", func(c *Config, v string) error {
+		c.Postgres.DSN = v
+		return nil
+	}},
+	{"migrate_on_start", "apply pending migrations on startup", func(c *Config, v string) error {
+		onStart, err := strconv.ParseBool(v)
+		c.Migrate.OnStart = onStart
+		return err
+	}},
+	{"auth_access_ttl", "lifetime of access tokens", func(c *Config, v string) error {
+		return setDuration(&c.Auth.AccessTTL, v)
+	}},
+	{"auth_refresh_ttl", "lifetime of refresh tokens", func(c *Config, v string) error {
+		return setDuration(&c.Auth.RefreshTTL, v)
+	}},
+	{"auth_key_grace", "how long a replaced signing key still verifies tokens", func(c *Config, v string) error {
+		return setDuration(&c.Auth.KeyGrace, v)
+	}},
+	{"auth_key_reload_interval", "how often signing key files are reread", func(c *Config, v string) error {
+		return setDuration(&c.Auth.KeyReloadInterval, v)
+	}},
+	{"log_level", "log level (debug, info, warn, error)", func(c *Config, v string) error {
+		c.Log.Level = v
+		return nil
+	}},
+	{"log_access_sample_rate", "share of successful requests in the access log (0..1)", func(c *Config, v string) error {
+		rate, err := strconv.ParseFloat(v, 64)
+		c.Log.AccessSampleRate = rate
+		return err
+	}},
+	{"tracing_exporter", "span exporter (none, stdout, file, otlp)", func(c *Config, v string) error {
+		c.Tracing.Exporter = v
+		return nil
+	}},
+	{"tracing_file", "file the file exporter appends spans to", func(c *Config, v string) error {
+		c.Tracing.File = v
+		return nil
+	}},
+	{"tracing_otlp_endpoint", "host:port of the OTLP/HTTP collector", func(c *Config, v string) error {
+		c.Tracing.OTLPEndpoint = v
+		return nil
+	}},
+	{"tracing_sample_ratio", "share of new traces that are sampled (0..1)", func(c *Config, v string) error {
+		ratio, err := strconv.ParseFloat(v, 64)
+		c.Tracing.SampleRatio = ratio
+		return err
+	}},
+	{"trash_retention", "how long deleted persons stay restorable (0 keeps them)", func(c *Config, v string) error {
+		return setDuration(&c.Trash.Retention, v)
+	}},
+	{"trash_purge_interval", "how often expired persons are purged from the trash", func(c *Config, v string) error {
+		return setDuration(&c.Trash.PurgeInterval, v)
+	}},
+	{"auth_enabled", "require session tokens on the persons API", func(c *Config, v string) error {
+		enabled, err := strconv.ParseBool(v)
+		c.Auth.Enabled = enabled
+		return err
+	}},
+	{"auth_jwt_secret", "secret used to sign session tokens", func(c *Config, v string) error {
+		c.Auth.JWTSecret = v
+		return nil
+	}},
+}
+
+func Default() *Config {
+	return &Config{
+		Storage: StoragePostgres,
+		Server: ServerConfig{
+			Addr:              ":8080",
+			ReadTimeout:       10 * time.Second,
+			ReadHeaderTimeout: 10 * time.Second,
+			WriteTimeout:      10 * time.Second,
+			RequestTimeout:    9 * time.Second,
+			StreamTimeout:     30 * time.Minute,
+			ShutdownTimeout:   15 * time.Second,
+			DrainDelay:        5 * time.Second,
+		},
+		Log: LogConfig{
+			Level:            "debug",
+			AccessSampleRate: 1,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			SampleRatio: 1,
+		},
+		Trash: TrashConfig{
+			Retention:     30 * 24 * time.Hour,
+			PurgeInterval: time.Hour,
+		},
+		Auth: AuthConfig{
+			Enabled:           true,
+			AccessTTL:         15 * time.Minute,
+			RefreshTTL:        7 * 24 * time.Hour,
+			KeyGrace:          7 * 24 * time.Hour,
+			KeyReloadInterval: time.Minute,
+			Policies: map[string][]string{
+				"GET /api/v1/persons":                     {"viewer", "editor", "admin"},
+				"GET /api/v1/persons/{personId}":          {"viewer", "editor", "admin"},
+				"POST /api/v1/persons":                    {"editor", "admin"},
+				"POST /api/v1/persons:batch":              {"editor", "admin"},
+				"GET /api/v1/persons/export":              {"viewer", "editor", "admin"},
+				"POST /api/v1/persons/import":             {"editor", "admin"},
+				"PUT /api/v1/persons/{personId}":          {"editor", "admin"},
+				"PATCH /api/v1/persons/{personId}":        {"editor", "admin"},
+				"DELETE /api/v1/persons/{personId}":       {"editor", "admin"},
+				"GET /api/v1/persons/trash":               {"admin"},
+				"POST /api/v1/persons/{personId}/restore": {"admin"},
+				"GET /api/v1/persons/{personId}/history":  {"editor", "admin"},
+				"POST /api/v1/persons/{personId}/revert":  {"editor", "admin"},
+			},
+		},
+	}
+}
+
+// Load builds the configuration from defaults, then the YAML file given by
+// -config or PERSON_SERVICE_CONFIG, then environment variables, then flags.
+func Load(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("person_service", flag.ContinueOnError)
+	path := fs.String(configKey, os.Getenv(envName(configKey)), "path to YAML config file")
+
+	flags := make(map[string]*string, len(options))
+	for _, opt := range options {
+		flags[opt.key] = fs.String(opt.key, "", opt.usage)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, errors.Wrap(err, "can`t parse flags")
+	}
+
+	cfg := Default()
+
+	if *path != "" {
+		if err := cfg.loadFile(*path); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, opt := range options {
+		v, ok := os.LookupEnv(envName(opt.key))
+		if !ok {
+			continue
+		}
+		if err := opt.set(cfg, v); err != nil {
+			return nil, errors.Wrapf(err, "bad value of %s", envName(opt.key))
+		}
+	}
+
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		for _, opt := range options {
+			if opt.key != f.Name || flagErr != nil {
+				continue
+			}
+			if err := opt.set(cfg, *flags[opt.key]); err != nil {
+				flagErr = errors.Wrapf(err, "bad value of -%s", opt.key)
+			}
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(err, "can`t read config file")
+	}
+
+	// Policies in the file replace the defaults instead of being merged
+	// into them, so that leaving a route out forbids it.
+	defaultPolicies := c.Auth.Policies
+	c.Auth.Policies = nil
+	if err = yaml.Unmarshal(data, c); err != nil {
+		return errors.Wrapf(err, "can`t parse config file %s", path)
+	}
+	if c.Auth.Policies == nil {
+		c.Auth.Policies = defaultPolicies
+	}
+
+	return nil
+}
+
+func (c *Config) Validate() error {
+	if c.Server.Addr == "" {
+		return errors.New("server.addr is required")
+	}
+	if c.Server.ReadTimeout <= 0 || c.Server.ReadHeaderTimeout <= 0 || c.Server.WriteTimeout <= 0 || c.Server.RequestTimeout <= 0 || c.Server.StreamTimeout <= 0 || c.Server.ShutdownTimeout <= 0 {
+		return errors.New("server timeouts must be positive")
+	}
+	if c.Server.DrainDelay < 0 {
+		return errors.New("server.drain_delay must not be negative")
+	}
+	switch c.Storage {
+	case StoragePostgres:
+		if c.Postgres.DSN == "" {
+			return errors.New("postgres.dsn is required")
+		}
+	case StorageMemory:
+		if c.Migrate.OnStart {
+			return errors.New("migrate.on_start requires postgres storage")
+		}
+	default:
+		return errors.Errorf("unknown storage %q", c.Storage)
+	}
+	if _, err := zapcore.ParseLevel(c.Log.Level); err != nil {
+		return errors.Wrap(err, "bad log.level")
+	}
+	if c.Log.AccessSampleRate < 0 || c.Log.AccessSampleRate > 1 {
+		return errors.New("log.access_sample_rate must be between 0 and 1")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		return errors.New("tracing.sample_ratio must be between 0 and 1")
+	}
+	if c.Tracing.Exporter == "file" && c.Tracing.File == "" {
+		return errors.New("tracing.file is required for the file exporter")
+	}
+	if c.Tracing.Exporter == "otlp" && c.Tracing.OTLPEndpoint == "" {
+		return errors.New("tracing.otlp_endpoint is required for the otlp exporter")
+	}
+	if c.Trash.Retention < 0 {
+		return errors.New("trash.retention must not be negative")
+	}
+	if c.Trash.Retention > 0 && c.Trash.PurgeInterval <= 0 {
+		return errors.New("trash.purge_interval must be positive")
+	}
+	if c.Auth.Enabled && c.Auth.JWTSecret == "" && len(c.Auth.Keys) == 0 {
+		return errors.New("auth.jwt_secret or auth.keys is required")
+	}
+	for i, key := range c.Auth.Keys {
+		if key.ID == "" || key.File == "" {
+			return errors.Errorf("auth.keys[%d] needs id and file", i)
+		}
+		switch key.Algorithm {
+		case "HS256", "RS256", "EdDSA":
+		default:
+			return errors.Errorf("auth.keys[%d] has unsupported algorithm %q", i, key.Algorithm)
+		}
+	}
+	if c.Auth.KeyGrace < 0 || c.Auth.KeyReloadInterval <= 0 {
+		return errors.New("auth.key_grace must not be negative and auth.key_reload_interval must be positive")
+	}
+	if c.Auth.AccessTTL <= 0 || c.Auth.RefreshTTL <= 0 {
+		return errors.New("auth token lifetimes must be positive")
+	}
+	for i, client := range c.Auth.Clients {
+		if client.ID == "" || client.SecretHash == "" || client.Role == "" {
+			return errors.Errorf("auth.clients[%d] needs id, secret_hash and role", i)
+		}
+	}
+
+	return nil
+}
+
+func envName(key string) string {
+	return envPrefix + strings.ToUpper(key)
+}
+
+func setDuration(dst *time.Duration, v string) error {
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return err
+	}
+
+	*dst = d
+	return nil
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+)
+
+type ConfigTestSuite struct {
+	suite.Suite
+}
+
+func TestConfigSuite(t *testing.T) {
+	suite.RunSuite(t, new(ConfigTestSuite))
+}
+
+func writeConfig(t provider.T) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(`
+server:
+  addr: ":9090"
+  write_timeout: 30s
+postgres:
+  dsn: "file-dsn"
+auth:
+  jwt_secret: "file-secret"
+`), 0o600)
+	t.Require().NoError(err)
+
+	return path
+}
+
+func (s *ConfigTestSuite) TestLoadFile(t provider.T) {
+	path := writeConfig(t)
+	cfg, err := Load([]string{"-config", path})
+	t.Require().NoError(err)
+
+	t.Assert().Equal(":9090", cfg.Server.Addr)
+	t.Assert().Equal(30*time.Second, cfg.Server.WriteTimeout)
+	t.Assert().Equal(10*time.Second, cfg.Server.ReadTimeout)
+	t.Assert().Equal("file-dsn", cfg.Postgres.DSN)
+	t.Assert().Equal("debug", cfg.Log.Level)
+}
+
+func (s *ConfigTestSuite) TestLoadFilePolicies(t provider.T) {
+	cfg, err := Load([]string{"-config", writeConfig(t)})
+	t.Require().NoError(err)
+	t.Assert().Equal(Default().Auth.Policies, cfg.Auth.Policies)
+
+	cases := map[string]struct {
+		Policies string
+		Expected map[string][]string
+	}{
+		"replaced": {
+			Policies: `{"GET /api/v1/persons": [admin]}`,
+			Expected: map[string][]string{"GET /api/v1/persons": {"admin"}},
+		},
+		"emptied": {
+			Policies: `{}`,
+			Expected: map[string][]string{},
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t provider.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			err := os.WriteFile(path, []byte("auth:\n  jwt_secret: s\n  policies: "+test.Policies+"\n"), 0o600)
+			t.Require().NoError(err)
+
+			cfg, err := Load([]string{"-config", path, "-postgres_dsn", "dsn"})
+			t.Require().NoError(err)
+			t.Assert().Equal(test.Expected, cfg.Auth.Policies)
+		})
+	}
+}
+
+func (s *ConfigTestSuite) TestPrecedence(t provider.T) {
+	path := writeConfig(t)
+	t.Setenv("PERSON_SERVICE_POSTGRES_DSN", "env-dsn")
+	t.Setenv("PERSON_SERVICE_SERVER_ADDR", ":7070")
+
+	cfg, err := Load([]string{"-config", path, "-server_addr", ":6060"})
+	t.Require().NoError(err)
+
+	t.Assert().Equal("env-dsn", cfg.Postgres.DSN)
+	t.Assert().Equal(":6060", cfg.Server.Addr)
+	t.Assert().Equal("file-secret", cfg.Auth.JWTSecret)
+}
+
+func (s *ConfigTestSuite) TestValidate(t provider.T) {
+	path := writeConfig(t)
+	cases := map[string]struct {
+		Args []string
+	}{
+		"no dsn": {
+			Args: []string{"-auth_jwt_secret", "secret"},
+		},
+		"bad log level": {
+			Args: []string{"-config", path, "-log_level", "loud"},
+		},
+		"bad timeout": {
+			Args: []string{"-config", path, "-server_read_timeout", "soon"},
+		},
+		"bad sample rate": {
+			Args: []string{"-config", path, "-log_access_sample_rate", "1.5"},
+		},
+		"no purge interval": {
+			Args: []string{"-config", path, "-trash_purge_interval", "0s"},
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t provider.T) {
+			_, err := Load(test.Args)
+			t.Assert().Error(err)
+		})
+	}
+}
@@ -8,7 +8,10 @@ import (
 
 type contextKeyType string
 
-const contextUserKey contextKeyType = "contextUserKey"
+const (
+	contextUserKey      contextKeyType = "contextUserKey"
+	contextRequestIDKey contextKeyType = "contextRequestIDKey"
+)
 
 type Manager struct{}
 
@@ -24,3 +27,16 @@ func (cu Manager) UserIDFromContext(ctx context.Context) (int, error) {
 
 	return user, nil
 }
+
+func (cu Manager) ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, contextRequestIDKey, requestID)
+}
+
+func (cu Manager) RequestIDFromContext(ctx context.Context) (string, error) {
+	requestID, ok := ctx.Value(contextRequestIDKey).(string)
+	if !ok {
+		return "", errors.Errorf("can`t get request id from context")
+	}
+
+	return requestID, nil
+}
@@ -0,0 +1,149 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Davmie/person_service/pkg/logger"
+	"github.com/Davmie/person_service/pkg/response"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+
+	defaultTimeout = 2 * time.Second
+)
+
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+type ComponentStatus struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components"`
+}
+
+// Registry runs the readiness checks registered by the service's
+// dependencies. All checks run concurrently and share Timeout.
+type Registry struct {
+	Timeout time.Duration
+	Logger  logger.Logger
+
+	mu           sync.RWMutex
+	names        []string
+	checkers     map[string]Checker
+	shuttingDown atomic.Bool
+}
+
+func New(logger logger.Logger) *Registry {
+	return &Registry{
+		Timeout:  defaultTimeout,
+		Logger:   logger,
+		checkers: map[string]Checker{},
+	}
+}
+
+// Register adds a readiness check; a check with the same name is replaced.
+func (hr *Registry) Register(name string, c Checker) {
+	hr.mu.Lock()
+	defer hr.mu.Unlock()
+
+	if _, ok := hr.checkers[name]; !ok {
+		hr.names = append(hr.names, name)
+		sort.Strings(hr.names)
+	}
+	hr.checkers[name] = c
+}
+
+// Shutdown marks the service as not ready, so that load balancers stop
+// routing new requests while in-flight ones drain.
+func (hr *Registry) Shutdown() {
+	hr.shuttingDown.Store(true)
+}
+
+func (hr *Registry) Check(ctx context.Context) Report {
+	hr.mu.RLock()
+	names := append([]string(nil), hr.names...)
+	checkers := make([]Checker, len(names))
+	for i, name := range names {
+		checkers[i] = hr.checkers[name]
+	}
+	hr.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, hr.Timeout)
+	defer cancel()
+
+	statuses := make([]ComponentStatus, len(names))
+	var wg sync.WaitGroup
+	for i := range checkers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			statuses[i] = run(ctx, checkers[i])
+		}(i)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Components: make(map[string]ComponentStatus, len(names))}
+	for i, name := range names {
+		report.Components[name] = statuses[i]
+		if statuses[i].Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	if hr.shuttingDown.Load() {
+		report.Status = StatusDown
+	}
+
+	return report
+}
+
+func run(ctx context.Context, c Checker) ComponentStatus {
+	start := time.Now()
+	err := c.Check(ctx)
+	status := ComponentStatus{
+		Status:    StatusUp,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		status.Status = StatusDown
+		status.Error = err.Error()
+	}
+
+	return status
+}
+
+// Live reports that the process is able to serve HTTP. It never checks
+// dependencies, so a database outage does not get the service restarted.
+func (hr *Registry) Live(w http.ResponseWriter, r *http.Request) {
+	response.JSON(w, hr.Logger, http.StatusOK, map[string]string{"status": StatusUp})
+}
+
+// Ready reports whether the service should receive traffic.
+func (hr *Registry) Ready(w http.ResponseWriter, r *http.Request) {
+	report := hr.Check(r.Context())
+
+	status := http.StatusOK
+	if report.Status != StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+
+	response.JSON(w, hr.Logger, status, report)
+}
@@ -0,0 +1,79 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+type HealthTestSuite struct {
+	suite.Suite
+	registry *Registry
+}
+
+func TestHealthSuite(t *testing.T) {
+	suite.RunSuite(t, new(HealthTestSuite))
+}
+
+func (s *HealthTestSuite) BeforeEach(t provider.T) {
+	s.registry = New(zap.NewNop().Sugar())
+	s.registry.Register("cache", CheckerFunc(func(ctx context.Context) error {
+		return nil
+	}))
+}
+
+func (s *HealthTestSuite) ready(t provider.T) (int, Report) {
+	w := httptest.NewRecorder()
+	s.registry.Ready(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var report Report
+	t.Require().NoError(json.Unmarshal(w.Body.Bytes(), &report))
+
+	return w.Code, report
+}
+
+func (s *HealthTestSuite) TestReady(t provider.T) {
+	code, report := s.ready(t)
+	t.Assert().Equal(http.StatusOK, code)
+	t.Assert().Equal(StatusUp, report.Status)
+	t.Assert().Equal(StatusUp, report.Components["cache"].Status)
+}
+
+func (s *HealthTestSuite) TestDependencyDown(t provider.T) {
+	s.registry.Timeout = 50 * time.Millisecond
+	s.registry.Register("postgres", CheckerFunc(func(ctx context.Context) error {
+		return errors.New("connection refused")
+	}))
+	s.registry.Register("slow", CheckerFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+
+	code, report := s.ready(t)
+	t.Assert().Equal(http.StatusServiceUnavailable, code)
+	t.Assert().Equal(StatusDown, report.Status)
+	t.Assert().Equal(StatusUp, report.Components["cache"].Status)
+	t.Assert().Equal("connection refused", report.Components["postgres"].Error)
+	t.Assert().Equal(StatusDown, report.Components["slow"].Status)
+	t.Assert().GreaterOrEqual(report.Components["slow"].LatencyMS, float64(50))
+}
+
+func (s *HealthTestSuite) TestShutdown(t provider.T) {
+	s.registry.Shutdown()
+
+	code, report := s.ready(t)
+	t.Assert().Equal(http.StatusServiceUnavailable, code)
+	t.Assert().Equal(StatusDown, report.Status)
+
+	w := httptest.NewRecorder()
+	s.registry.Live(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	t.Assert().Equal(http.StatusOK, w.Code)
+}
@@ -1,7 +1,63 @@
 package logger
 
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
 type Logger interface {
 	Debugw(string, ...interface{})
 	Infow(string, ...interface{})
 	Errorw(string, ...interface{})
 }
+
+type contextKeyType string
+
+const contextLoggerKey contextKeyType = "contextLoggerKey"
+
+// With returns a child of l that adds keysAndValues to every entry.
+func With(l Logger, keysAndValues ...interface{}) Logger {
+	if zl, ok := l.(*zap.SugaredLogger); ok {
+		return zl.With(keysAndValues...)
+	}
+
+	return &fieldsLogger{next: l, fields: keysAndValues}
+}
+
+// ContextWith stores the request-scoped logger l in ctx.
+func ContextWith(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, contextLoggerKey, l)
+}
+
+// FromContext returns the request-scoped logger stored in ctx, or fallback
+// outside of a request.
+func FromContext(ctx context.Context, fallback Logger) Logger {
+	if l, ok := ctx.Value(contextLoggerKey).(Logger); ok {
+		return l
+	}
+
+	return fallback
+}
+
+type fieldsLogger struct {
+	next   Logger
+	fields []interface{}
+}
+
+func (fl *fieldsLogger) with(keysAndValues []interface{}) []interface{} {
+	all := make([]interface{}, 0, len(fl.fields)+len(keysAndValues))
+	return append(append(all, fl.fields...), keysAndValues...)
+}
+
+func (fl *fieldsLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	fl.next.Debugw(msg, fl.with(keysAndValues)...)
+}
+
+func (fl *fieldsLogger) Infow(msg string, keysAndValues ...interface{}) {
+	fl.next.Infow(msg, fl.with(keysAndValues)...)
+}
+
+func (fl *fieldsLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	fl.next.Errorw(msg, fl.with(keysAndValues)...)
+}
@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Davmie/person_service/models"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "person_service"
+
+// UnmatchedRoute labels requests that no route pattern served, so that
+// scans of random paths do not create a series per path.
+const UnmatchedRoute = "unmatched"
+
+// Metrics owns a private registry, so tests can create as many instances as
+// they need without clashing on the global one.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	inFlight        *prometheus.GaugeVec
+	queryDuration   *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "route", "code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method, route pattern and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "code"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "HTTP requests currently being served by route pattern.",
+		}, []string{"route"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "repository_query_duration_seconds",
+			Help:      "Repository call latency by repository, method and result.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"repository", "method", "result"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.inFlight,
+		m.queryDuration,
+	)
+
+	return m
+}
+
+// RegisterDB exports the connection pool statistics of db labelled with
+// name.
+func (m *Metrics) RegisterDB(name string, db *sql.DB) error {
+	return m.registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// RequestStarted increments the in-flight gauge of route and returns a func
+// that decrements it and records the finished request.
+func (m *Metrics) RequestStarted(method, route string) func(code int) {
+	start := time.Now()
+	m.inFlight.WithLabelValues(route).Inc()
+
+	return func(code int) {
+		m.inFlight.WithLabelValues(route).Dec()
+
+		status := strconv.Itoa(code)
+		m.requests.WithLabelValues(method, route, status).Inc()
+		m.requestDuration.WithLabelValues(method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// ObserveQuery records a repository call that started at start.
+func (m *Metrics) ObserveQuery(repository, method string, start time.Time, err error) {
+	m.queryDuration.WithLabelValues(repository, method, queryResult(err)).Observe(time.Since(start).Seconds())
+}
+
+// queryResult labels the outcome of a repository call. Missing records and
+// version conflicts are part of normal operation and are kept apart from
+// failures of the store.
+func queryResult(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, models.ErrNotFound):
+		return "not_found"
+	case errors.Is(err, models.ErrConflict), errors.Is(err, models.ErrPreconditionFailed):
+		return "conflict"
+	default:
+		return "error"
+	}
+}
@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/Davmie/person_service/models"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/pkg/errors"
+)
+
+type MetricsTestSuite struct {
+	suite.Suite
+}
+
+func TestMetricsSuite(t *testing.T) {
+	suite.RunSuite(t, new(MetricsTestSuite))
+}
+
+func (s *MetricsTestSuite) TestQueryResult(t provider.T) {
+	cases := map[string]struct {
+		Err    error
+		Result string
+	}{
+		"success":          {Err: nil, Result: "ok"},
+		"not found":        {Err: errors.Wrap(models.ErrNotFound, "Get"), Result: "not_found"},
+		"conflict":         {Err: errors.Wrap(models.ErrConflict, "Create"), Result: "conflict"},
+		"stale version":    {Err: errors.Wrap(models.ErrPreconditionFailed, "Update"), Result: "conflict"},
+		"database failure": {Err: errors.New("connection refused"), Result: "error"},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t provider.T) {
+			t.Assert().Equal(test.Result, queryResult(test.Err))
+		})
+	}
+}
@@ -1,23 +1,51 @@
 package middleware
 
 import (
+	"math/rand"
 	"net/http"
-
 	"time"
 
+	pkgContext "github.com/Davmie/person_service/pkg/context"
 	"github.com/Davmie/person_service/pkg/logger"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func AccessLog(logger logger.Logger, next http.Handler) http.Handler {
+// sampled reports whether a successful request is logged at the given rate.
+func sampled(rate float64) bool {
+	return rate >= 1 || rand.Float64() < rate
+}
+
+// AccessLog logs every failed or panicked request and a sampleRate share of
+// the successful ones.
+func AccessLog(logger logger.Logger, route RouteFunc, sampleRate float64, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+		rw := Instrument(w)
+
+		next.ServeHTTP(rw, r)
+
+		if rw.Status < http.StatusBadRequest && !rw.Panicked && !sampled(sampleRate) {
+			return
+		}
 
-		next.ServeHTTP(w, r)
+		requestID, _ := pkgContext.Manager{}.RequestIDFromContext(r.Context())
+		traceID := ""
+		if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+			traceID = sc.TraceID().String()
+		}
 
 		logger.Infow("New request",
 			"method", r.Method,
 			"remote_addr", r.RemoteAddr,
 			"url", r.URL.Path,
+			"route", route(r),
+			"status", rw.Status,
+			"bytes", rw.Bytes,
+			"panicked", rw.Panicked,
+			"user_id", rw.UserID,
+			"request_id", requestID,
+			"trace_id", traceID,
+			"user_agent", r.UserAgent(),
 			"time", time.Since(start),
 		)
 	})
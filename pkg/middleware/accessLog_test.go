@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type AccessLogTestSuite struct {
+	suite.Suite
+	logs   *observer.ObservedLogs
+	router http.Handler
+}
+
+func TestAccessLogSuite(t *testing.T) {
+	suite.RunSuite(t, new(AccessLogTestSuite))
+}
+
+func (s *AccessLogTestSuite) BeforeEach(t provider.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	logger := zap.New(core).Sugar()
+	s.logs = logs
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /persons/{personId}", func(w http.ResponseWriter, r *http.Request) {
+		setUserID(w, 7)
+		if r.PathValue("personId") == "0" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("{}"))
+	})
+	mux.HandleFunc("GET /panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	s.router = AccessLog(logger, MuxRoute(mux), 0, Panic(logger, mux))
+}
+
+func (s *AccessLogTestSuite) serve(path string) int {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	r.Header.Set("User-Agent", "tests")
+	s.router.ServeHTTP(w, r)
+
+	return w.Code
+}
+
+func (s *AccessLogTestSuite) TestSampling(t provider.T) {
+	t.Assert().Equal(http.StatusOK, s.serve("/persons/1"))
+	t.Assert().Equal(0, s.logs.Len(), "successful requests are sampled out")
+
+	t.Assert().Equal(http.StatusNotFound, s.serve("/persons/0"))
+	entries := s.logs.FilterMessage("New request").AllUntimed()
+	t.Require().Len(entries, 1)
+
+	fields := entries[0].ContextMap()
+	t.Assert().Equal(int64(http.StatusNotFound), fields["status"])
+	t.Assert().Equal(int64(len("not found\n")), fields["bytes"])
+	t.Assert().Equal("GET /persons/{personId}", fields["route"])
+	t.Assert().Equal(int64(7), fields["user_id"])
+	t.Assert().Equal("tests", fields["user_agent"])
+	t.Assert().Equal(false, fields["panicked"])
+}
+
+func (s *AccessLogTestSuite) TestPanic(t provider.T) {
+	t.Assert().Equal(http.StatusInternalServerError, s.serve("/panic"))
+
+	entries := s.logs.FilterMessage("New request").AllUntimed()
+	t.Require().Len(entries, 1)
+	t.Assert().Equal(true, entries[0].ContextMap()["panicked"])
+	t.Assert().Equal(int64(http.StatusInternalServerError), entries[0].ContextMap()["status"])
+}
+
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (hr *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hr.hijacked = true
+	return nil, nil, nil
+}
+
+func (s *AccessLogTestSuite) TestInterfaces(t provider.T) {
+	recorder := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	rw := Instrument(recorder)
+	t.Assert().Same(rw, Instrument(rw))
+
+	rw.Flush()
+	t.Assert().True(recorder.Flushed)
+
+	_, _, err := rw.Hijack()
+	t.Assert().NoError(err)
+	t.Assert().True(recorder.hijacked)
+
+	_, _, err = Instrument(httptest.NewRecorder()).Hijack()
+	t.Assert().ErrorIs(err, http.ErrNotSupported)
+}
@@ -3,14 +3,19 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"strings"
 
 	"github.com/Davmie/person_service/pkg/logger"
+	"github.com/Davmie/person_service/pkg/response"
 )
 
-const sessionHeader = "Authorization"
+const (
+	sessionHeader = "Authorization"
+	bearerPrefix  = "Bearer "
+)
 
 type AuthSessionsManager interface {
-	GetUser(string) (int, string, error)
+	GetUser(context.Context, string) (int, string, error)
 }
 
 type AuthContextManager interface {
@@ -25,28 +30,28 @@ type AuthManager struct {
 
 func (am *AuthManager) Auth(next http.Handler, roles ...string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		token := r.Header.Get(sessionHeader)
+		token := strings.TrimPrefix(r.Header.Get(sessionHeader), bearerPrefix)
 		if token == "" {
-			am.Logger.Infow("authorization",
+			logger.FromContext(r.Context(), am.Logger).Infow("authorization",
 				"url", r.URL.Path,
 				"method", r.Method,
 				"remote_addr", r.RemoteAddr,
 				"auth result", "session header not found")
 
-			http.Error(w, "no auth", http.StatusUnauthorized)
+			response.Message(w, am.Logger, http.StatusUnauthorized, "no auth")
 			return
 		}
 
-		userID, userRole, err := am.SessionManager.GetUser(token)
+		userID, userRole, err := am.SessionManager.GetUser(r.Context(), token)
 		if err != nil {
-			am.Logger.Infow("authorization",
+			logger.FromContext(r.Context(), am.Logger).Infow("authorization",
 				"url", r.URL.Path,
 				"method", r.Method,
 				"remote_addr", r.RemoteAddr,
 				"auth result", "user not found",
 				"GetUser error", err)
 
-			http.Error(w, "no auth", http.StatusUnauthorized)
+			response.Message(w, am.Logger, http.StatusUnauthorized, "no auth")
 			return
 		}
 
@@ -59,19 +64,19 @@ func (am *AuthManager) Auth(next http.Handler, roles ...string) http.Handler {
 				}
 			}
 			if !roleMatch {
-				am.Logger.Infow("authorization",
+				logger.FromContext(r.Context(), am.Logger).Infow("authorization",
 					"url", r.URL.Path,
 					"method", r.Method,
 					"remote_addr", r.RemoteAddr,
 					"auth result", "user role doesn`t match",
 					"userID", userID,
 					"userRole", userRole)
-				http.Error(w, "Forbidden", http.StatusForbidden)
+				response.Message(w, am.Logger, http.StatusForbidden, "forbidden")
 				return
 			}
 		}
 
-		am.Logger.Infow("authorization",
+		logger.FromContext(r.Context(), am.Logger).Infow("authorization",
 			"url", r.URL.Path,
 			"method", r.Method,
 			"remote_addr", r.RemoteAddr,
@@ -79,7 +84,28 @@ func (am *AuthManager) Auth(next http.Handler, roles ...string) http.Handler {
 			"userID", userID,
 			"userRole", userRole)
 
+		setUserID(w, userID)
 		ctx := am.ContextManager.ContextWithUserID(r.Context(), userID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// RoutePolicy maps a route pattern, as registered in http.ServeMux, to the
+// roles allowed to call it.
+type RoutePolicy map[string][]string
+
+// Route protects next with the roles the policy lists for pattern. Routes
+// missing from the policy are forbidden for everyone.
+func (am *AuthManager) Route(policy RoutePolicy, pattern string, next http.Handler) http.Handler {
+	roles, ok := policy[pattern]
+	if !ok || len(roles) == 0 {
+		am.Logger.Errorw("no auth policy for route, denying all requests",
+			"pattern", pattern)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			response.Message(w, am.Logger, http.StatusForbidden, "forbidden")
+		})
+	}
+
+	return am.Auth(next, roles...)
+}
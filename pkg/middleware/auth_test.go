@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	pkgContext "github.com/Davmie/person_service/pkg/context"
+	"github.com/Davmie/person_service/pkg/session"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"go.uber.org/zap"
+)
+
+type AuthTestSuite struct {
+	suite.Suite
+	sessions session.JWTSessionsManager
+	router   http.Handler
+}
+
+func TestAuthSuite(t *testing.T) {
+	suite.RunSuite(t, new(AuthTestSuite))
+}
+
+func (s *AuthTestSuite) BeforeEach(t provider.T) {
+	s.sessions = session.NewJWTSessionsManager("test-secret")
+	am := AuthManager{
+		SessionManager: s.sessions,
+		Logger:         zap.NewNop().Sugar(),
+		ContextManager: pkgContext.Manager{},
+	}
+	policy := RoutePolicy{
+		"GET /persons":  {"viewer", "editor"},
+		"POST /persons": {"editor"},
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := pkgContext.Manager{}.UserIDFromContext(r.Context())
+		if err != nil || userID != 42 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	mux := http.NewServeMux()
+	for _, pattern := range []string{"GET /persons", "POST /persons", "DELETE /persons"} {
+		mux.Handle(pattern, am.Route(policy, pattern, handler))
+	}
+	s.router = mux
+}
+
+func (s *AuthTestSuite) token(t provider.T, role string) string {
+	token, err := s.sessions.CreateSession(42, role)
+	t.Require().NoError(err)
+
+	return token
+}
+
+func (s *AuthTestSuite) TestRoutePolicy(t provider.T) {
+	foreignToken, err := session.NewJWTSessionsManager("other-secret").CreateSession(42, "editor")
+	t.Require().NoError(err)
+
+	cases := map[string]struct {
+		Method string
+		Header string
+		Status int
+	}{
+		"no token": {
+			Method: http.MethodGet,
+			Status: http.StatusUnauthorized,
+		},
+		"malformed token": {
+			Method: http.MethodGet,
+			Header: "Bearer garbage",
+			Status: http.StatusUnauthorized,
+		},
+		"foreign token": {
+			Method: http.MethodGet,
+			Header: "Bearer " + foreignToken,
+			Status: http.StatusUnauthorized,
+		},
+		"viewer reads": {
+			Method: http.MethodGet,
+			Header: "Bearer " + s.token(t, "viewer"),
+			Status: http.StatusOK,
+		},
+		"viewer writes": {
+			Method: http.MethodPost,
+			Header: "Bearer " + s.token(t, "viewer"),
+			Status: http.StatusForbidden,
+		},
+		"editor writes": {
+			Method: http.MethodPost,
+			Header: s.token(t, "editor"),
+			Status: http.StatusOK,
+		},
+		"route without policy": {
+			Method: http.MethodDelete,
+			Header: "Bearer " + s.token(t, "editor"),
+			Status: http.StatusForbidden,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t provider.T) {
+			req := httptest.NewRequest(test.Method, "/persons", nil)
+			if test.Header != "" {
+				req.Header.Set("Authorization", test.Header)
+			}
+			rec := httptest.NewRecorder()
+
+			s.router.ServeHTTP(rec, req)
+			t.Assert().Equal(test.Status, rec.Code)
+		})
+	}
+}
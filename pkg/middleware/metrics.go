@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Davmie/person_service/pkg/metrics"
+)
+
+// RouteFunc returns the pattern of the route that serves r, e.g.
+// "GET /api/v1/persons/{personId}", or "" if none does.
+type RouteFunc func(r *http.Request) string
+
+// MuxRoute resolves routes with the patterns registered on mux.
+func MuxRoute(mux *http.ServeMux) RouteFunc {
+	return func(r *http.Request) string {
+		_, pattern := mux.Handler(r)
+		return pattern
+	}
+}
+
+// Metrics counts and times requests by route. It must wrap Panic, so that
+// panicking requests are still recorded.
+func Metrics(m *metrics.Metrics, route RouteFunc, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pattern := route(r)
+		if pattern == "" {
+			pattern = metrics.UnmatchedRoute
+		}
+
+		done := m.RequestStarted(r.Method, pattern)
+		rw := Instrument(w)
+		next.ServeHTTP(rw, r)
+
+		// Panics are recovered by the Panic middleware wrapped inside, which
+		// marks rw; one that happens after the header was sent still counts
+		// as a server error.
+		status := rw.Status
+		if rw.Panicked {
+			status = http.StatusInternalServerError
+		}
+		done(status)
+	})
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Davmie/person_service/pkg/metrics"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+type MetricsTestSuite struct {
+	suite.Suite
+	metrics *metrics.Metrics
+	router  http.Handler
+}
+
+func TestMetricsSuite(t *testing.T) {
+	suite.RunSuite(t, new(MetricsTestSuite))
+}
+
+func (s *MetricsTestSuite) BeforeEach(t provider.T) {
+	s.metrics = metrics.New()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /persons/{personId}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("personId") == "0" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		io.WriteString(w, "{}")
+	})
+	mux.HandleFunc("DELETE /persons/{personId}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("personId") == "0" {
+			io.WriteString(w, "{")
+		}
+		panic("boom")
+	})
+	s.router = Metrics(s.metrics, MuxRoute(mux), Panic(zap.NewNop().Sugar(), mux))
+}
+
+func (s *MetricsTestSuite) scrape(t provider.T) string {
+	w := httptest.NewRecorder()
+	s.metrics.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	t.Require().Equal(http.StatusOK, w.Code)
+
+	return w.Body.String()
+}
+
+func (s *MetricsTestSuite) TestRequests(t provider.T) {
+	for _, path := range []string{"/persons/1", "/persons/2", "/persons/0", "/unknown"} {
+		s.router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	body := s.scrape(t)
+	t.Assert().Contains(body, `person_service_http_requests_total{code="200",method="GET",route="GET /persons/{personId}"} 2`)
+	t.Assert().Contains(body, `person_service_http_requests_total{code="404",method="GET",route="GET /persons/{personId}"} 1`)
+	t.Assert().Contains(body, `person_service_http_requests_total{code="404",method="GET",route="unmatched"} 1`)
+	t.Assert().Contains(body, `person_service_http_request_duration_seconds_count{code="200",method="GET",route="GET /persons/{personId}"} 2`)
+	t.Assert().Contains(body, `person_service_http_requests_in_flight{route="GET /persons/{personId}"} 0`)
+	t.Assert().Contains(body, "go_goroutines")
+}
+
+func (s *MetricsTestSuite) TestPanic(t provider.T) {
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/persons/1", nil))
+	t.Assert().Equal(http.StatusInternalServerError, w.Code)
+
+	// The body was already started, so the client sees 200.
+	w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/persons/0", nil))
+	t.Assert().Equal(http.StatusOK, w.Code)
+
+	body := s.scrape(t)
+	t.Assert().Contains(body, `person_service_http_requests_total{code="500",method="DELETE",route="DELETE /persons/{personId}"} 2`)
+	t.Assert().Contains(body, `person_service_http_requests_in_flight{route="DELETE /persons/{personId}"} 0`)
+}
+
+func (s *MetricsTestSuite) TestQueries(t provider.T) {
+	s.metrics.ObserveQuery("person", "Get", time.Now(), nil)
+	s.metrics.ObserveQuery("person", "Get", time.Now(), errors.New("not found"))
+
+	body := s.scrape(t)
+	t.Assert().Contains(body, `person_service_repository_query_duration_seconds_count{method="Get",repository="person",result="ok"} 1`)
+	t.Assert().Contains(body, `person_service_repository_query_duration_seconds_count{method="Get",repository="person",result="error"} 1`)
+}
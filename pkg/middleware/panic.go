@@ -1,28 +1,31 @@
 package middleware
 
 import (
-	"fmt"
 	"net/http"
 
 	"github.com/Davmie/person_service/pkg/logger"
+	"github.com/Davmie/person_service/pkg/response"
 )
 
-func Panic(logger logger.Logger, next http.Handler) http.Handler {
+func Panic(l logger.Logger, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := Instrument(w)
 
 		defer func() {
 			if err := recover(); err != nil {
-				logger.Errorw("Server paniced",
+				rw.Panicked = true
+				logger.FromContext(r.Context(), l).Errorw("Server paniced",
 					"method", r.Method,
 					"remote_addr", r.RemoteAddr,
 					"url", r.URL.Path,
 					"error", err,
 				)
 
-				fmt.Println("recovered", err)
-				http.Error(w, "Internal server error", 500)
+				if !rw.WroteHeader() {
+					response.Message(rw, l, http.StatusInternalServerError, "internal server error")
+				}
 			}
 		}()
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(rw, r)
 	})
 }
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+
+	pkgContext "github.com/Davmie/person_service/pkg/context"
+	"github.com/Davmie/person_service/pkg/logger"
+	"github.com/Davmie/person_service/pkg/response"
+	"github.com/google/uuid"
+)
+
+// validRequestID limits client supplied ids to what is safe to log and echo.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID accepts the client's X-Request-ID or generates one, echoes it in
+// the response and stores it with a request-scoped logger in the context.
+func RequestID(l logger.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(response.RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		w.Header().Set(response.RequestIDHeader, requestID)
+
+		ctx := pkgContext.Manager{}.ContextWithRequestID(r.Context(), requestID)
+		ctx = logger.ContextWith(ctx, logger.With(l, "request_id", requestID))
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
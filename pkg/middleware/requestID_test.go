@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	pkgContext "github.com/Davmie/person_service/pkg/context"
+	"github.com/Davmie/person_service/pkg/logger"
+	"github.com/Davmie/person_service/pkg/response"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type RequestIDTestSuite struct {
+	suite.Suite
+	logs   *observer.ObservedLogs
+	router http.Handler
+}
+
+func TestRequestIDSuite(t *testing.T) {
+	suite.RunSuite(t, new(RequestIDTestSuite))
+}
+
+func (s *RequestIDTestSuite) BeforeEach(t provider.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	base := zap.New(core).Sugar()
+	s.logs = logs
+
+	s.router = RequestID(base, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID, err := pkgContext.Manager{}.RequestIDFromContext(r.Context())
+		if err != nil || requestID != w.Header().Get(response.RequestIDHeader) {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		logger.FromContext(r.Context(), base).Infow("handling")
+		response.Message(w, base, http.StatusNotFound, "not found")
+	}))
+}
+
+func (s *RequestIDTestSuite) serve(requestID string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/persons/1", nil)
+	if requestID != "" {
+		r.Header.Set(response.RequestIDHeader, requestID)
+	}
+	s.router.ServeHTTP(w, r)
+
+	return w
+}
+
+func (s *RequestIDTestSuite) TestAcceptsClientID(t provider.T) {
+	w := s.serve("abc-123")
+	t.Require().Equal(http.StatusNotFound, w.Code)
+	t.Assert().Equal("abc-123", w.Header().Get(response.RequestIDHeader))
+
+	var body response.ErrorResponse
+	t.Require().NoError(json.Unmarshal(w.Body.Bytes(), &body))
+	t.Assert().Equal("abc-123", body.RequestID)
+
+	entries := s.logs.FilterMessage("handling").AllUntimed()
+	t.Require().Len(entries, 1)
+	t.Assert().Equal("abc-123", entries[0].ContextMap()["request_id"])
+}
+
+func (s *RequestIDTestSuite) TestGeneratesID(t provider.T) {
+	for name, requestID := range map[string]string{
+		"missing":  "",
+		"too long": strings.Repeat("a", 129),
+		"unsafe":   "id\nwith newline",
+	} {
+		t.Run(name, func(t provider.T) {
+			w := s.serve(requestID)
+			t.Require().Equal(http.StatusNotFound, w.Code)
+			t.Assert().Len(w.Header().Get(response.RequestIDHeader), 36)
+			t.Assert().NotEqual(requestID, w.Header().Get(response.RequestIDHeader))
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// ResponseWriter records what the handlers wrote, so that the outer
+// middlewares can log and measure it. A single instance is shared by all
+// middlewares of a request: Instrument returns w itself if it already is
+// one.
+type ResponseWriter struct {
+	http.ResponseWriter
+	Status   int
+	Bytes    int64
+	Panicked bool
+	// UserID is set by the auth middleware, -1 for anonymous requests.
+	UserID int
+
+	wroteHeader bool
+}
+
+func Instrument(w http.ResponseWriter) *ResponseWriter {
+	if rw, ok := w.(*ResponseWriter); ok {
+		return rw
+	}
+
+	return &ResponseWriter{ResponseWriter: w, Status: http.StatusOK, UserID: -1}
+}
+
+func (rw *ResponseWriter) WriteHeader(status int) {
+	if !rw.wroteHeader {
+		rw.Status = status
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *ResponseWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	n, err := rw.ResponseWriter.Write(b)
+	rw.Bytes += int64(n)
+
+	return n, err
+}
+
+// WroteHeader reports whether the response has been started, after which
+// the status can no longer be changed.
+func (rw *ResponseWriter) WroteHeader() bool {
+	return rw.wroteHeader
+}
+
+func (rw *ResponseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		rw.wroteHeader = true
+		f.Flush()
+	}
+}
+
+func (rw *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.Wrap(http.ErrNotSupported, "response writer can`t be hijacked")
+	}
+
+	return h.Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rw *ResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// setUserID annotates the shared writer of the request, if there is one,
+// with the authenticated user.
+func setUserID(w http.ResponseWriter, userID int) {
+	if rw, ok := w.(*ResponseWriter); ok {
+		rw.UserID = userID
+	}
+}
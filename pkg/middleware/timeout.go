@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Timeout bounds the context of a request by timeout. Requests to the route
+// patterns in routes get their own timeout instead, which also moves the read
+// and write deadlines of the connection, so that the server timeouts do not
+// cut streamed bodies short.
+func Timeout(timeout time.Duration, route RouteFunc, routes map[string]time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d := timeout
+		if routeTimeout, ok := routes[route(r)]; ok {
+			d = routeTimeout
+
+			rc := http.NewResponseController(w)
+			deadline := time.Now().Add(d)
+			_ = rc.SetReadDeadline(deadline)
+			_ = rc.SetWriteDeadline(deadline)
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		defer cancel()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
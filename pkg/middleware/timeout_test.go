@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+)
+
+type TimeoutTestSuite struct {
+	suite.Suite
+}
+
+func TestTimeoutSuite(t *testing.T) {
+	suite.RunSuite(t, new(TimeoutTestSuite))
+}
+
+func (s *TimeoutTestSuite) TestRouteTimeout(t provider.T) {
+	slow := func(w http.ResponseWriter, r *http.Request) {
+		deadline, _ := r.Context().Deadline()
+		select {
+		case <-time.After(300 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+		io.WriteString(w, time.Until(deadline).Round(time.Second).String())
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /export", slow)
+	mux.HandleFunc("GET /persons", slow)
+
+	server := httptest.NewUnstartedServer(Timeout(time.Second, MuxRoute(mux), map[string]time.Duration{
+		"GET /export": time.Minute,
+	}, mux))
+	server.Config.WriteTimeout = 100 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/export")
+	t.Require().NoError(err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	t.Require().NoError(err)
+	t.Assert().Equal("1m0s", string(body))
+
+	_, err = http.Get(server.URL + "/persons")
+	t.Assert().Error(err, "the write deadline of other routes is kept")
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Davmie/person_service/pkg/logger"
+	"github.com/Davmie/person_service/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing continues the trace of an incoming traceparent header, or starts a
+// new one, with a server span named after the route. The trace id is added
+// to the request-scoped logger.
+func Tracing(l logger.Logger, route RouteFunc, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		pattern := route(r)
+		name := pattern
+		if name == "" {
+			name = r.Method
+		}
+
+		ctx, span := tracing.Tracer().Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+				semconv.HTTPRoute(pattern),
+				semconv.UserAgentOriginal(r.UserAgent()),
+			),
+		)
+		defer span.End()
+
+		if sc := span.SpanContext(); sc.IsValid() {
+			ctx = logger.ContextWith(ctx, logger.With(logger.FromContext(ctx, l),
+				"trace_id", sc.TraceID().String(),
+				"span_id", sc.SpanID().String()))
+		}
+
+		rw := Instrument(w)
+		next.ServeHTTP(rw, r.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPResponseStatusCode(rw.Status))
+		if rw.Status >= http.StatusInternalServerError || rw.Panicked {
+			span.SetStatus(codes.Error, http.StatusText(rw.Status))
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Davmie/person_service/pkg/config"
+	"github.com/Davmie/person_service/pkg/logger"
+	"github.com/Davmie/person_service/pkg/tracing"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+const (
+	parentTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	parentSpanID  = "00f067aa0ba902b7"
+)
+
+type TracingTestSuite struct {
+	suite.Suite
+	exporter *tracetest.InMemoryExporter
+	provider *sdktrace.TracerProvider
+	logs     *observer.ObservedLogs
+	router   http.Handler
+}
+
+func TestTracingSuite(t *testing.T) {
+	suite.RunSuite(t, new(TracingTestSuite))
+}
+
+func (s *TracingTestSuite) BeforeEach(t provider.T) {
+	_, err := tracing.Setup(context.Background(), config.TracingConfig{Exporter: "none"})
+	t.Require().NoError(err)
+
+	s.exporter = tracetest.NewInMemoryExporter()
+	s.provider = tracing.NewProvider(s.exporter, 1)
+	otel.SetTracerProvider(s.provider)
+
+	core, logs := observer.New(zap.InfoLevel)
+	base := zap.New(core).Sugar()
+	s.logs = logs
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /persons/{personId}", func(w http.ResponseWriter, r *http.Request) {
+		_, span := tracing.Tracer().Start(r.Context(), "personUseCase.Get")
+		span.End()
+
+		logger.FromContext(r.Context(), base).Infow("handling")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	s.router = Tracing(base, MuxRoute(mux), mux)
+}
+
+func (s *TracingTestSuite) TestContinuesTrace(t provider.T) {
+	r := httptest.NewRequest(http.MethodGet, "/persons/1", nil)
+	r.Header.Set("traceparent", "00-"+parentTraceID+"-"+parentSpanID+"-01")
+	s.router.ServeHTTP(httptest.NewRecorder(), r)
+
+	t.Require().NoError(s.provider.ForceFlush(context.Background()))
+	spans := s.exporter.GetSpans()
+	t.Require().Len(spans, 2)
+
+	child, server := spans[0], spans[1]
+	t.Assert().Equal("GET /persons/{personId}", server.Name)
+	t.Assert().Equal(parentTraceID, server.SpanContext.TraceID().String())
+	t.Assert().Equal(parentSpanID, server.Parent.SpanID().String())
+	t.Assert().True(server.Parent.IsRemote())
+	t.Assert().Equal(codes.Error, server.Status.Code)
+	t.Assert().Equal(server.SpanContext.SpanID(), child.Parent.SpanID())
+
+	entries := s.logs.FilterMessage("handling").AllUntimed()
+	t.Require().Len(entries, 1)
+	t.Assert().Equal(parentTraceID, entries[0].ContextMap()["trace_id"])
+}
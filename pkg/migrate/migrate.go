@@ -0,0 +1,248 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Davmie/person_service/pkg/logger"
+	"github.com/pkg/errors"
+)
+
+// lockID is the key of the advisory lock held while migrating, so that
+// replicas starting at the same time apply migrations one after another.
+const lockID = 7163302
+
+var fileRe = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+type Migrator struct {
+	DB         *sql.DB
+	Logger     logger.Logger
+	Migrations []Migration
+}
+
+// New reads migrations named <version>_<name>.(up|down).sql from the root
+// of fsys. Every version must have an up file; down files are optional.
+func New(db *sql.DB, log logger.Logger, fsys fs.FS) (*Migrator, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, errors.Wrap(err, "can`t read migrations")
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		m := fileRe.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "bad migration version in %s", e.Name())
+		}
+
+		body, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, errors.Wrapf(err, "can`t read migration %s", e.Name())
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != m[2] {
+			return nil, errors.Errorf("migration %d has different names: %s and %s", version, mig.Name, m[2])
+		}
+
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, errors.Errorf("migration %d_%s has no up file", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return &Migrator{DB: db, Logger: log, Migrations: migrations}, nil
+}
+
+// Up applies all pending migrations and returns them.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+
+	err := m.withLock(ctx, func(conn *sql.Conn, done map[int64]time.Time) error {
+		for _, mig := range m.Migrations {
+			if _, ok := done[mig.Version]; ok {
+				continue
+			}
+
+			err := inTx(ctx, conn, mig.Up,
+				`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, mig.Version, mig.Name)
+			if err != nil {
+				return errors.Wrapf(err, "can`t apply migration %d_%s", mig.Version, mig.Name)
+			}
+
+			m.Logger.Infow("migration applied",
+				"version", mig.Version,
+				"name", mig.Name)
+			applied = append(applied, mig)
+		}
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations and returns them.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+
+	err := m.withLock(ctx, func(conn *sql.Conn, done map[int64]time.Time) error {
+		for i := len(m.Migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			mig := m.Migrations[i]
+			if _, ok := done[mig.Version]; !ok {
+				continue
+			}
+			if mig.Down == "" {
+				return errors.Errorf("migration %d_%s can`t be reverted", mig.Version, mig.Name)
+			}
+
+			err := inTx(ctx, conn, mig.Down,
+				`DELETE FROM schema_migrations WHERE version = $1`, mig.Version)
+			if err != nil {
+				return errors.Wrapf(err, "can`t revert migration %d_%s", mig.Version, mig.Name)
+			}
+
+			m.Logger.Infow("migration reverted",
+				"version", mig.Version,
+				"name", mig.Name)
+			reverted = append(reverted, mig)
+		}
+		return nil
+	})
+
+	return reverted, err
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+
+	err := m.withLock(ctx, func(conn *sql.Conn, done map[int64]time.Time) error {
+		for _, mig := range m.Migrations {
+			st := Status{Migration: mig}
+			if at, ok := done[mig.Version]; ok {
+				st.AppliedAt = &at
+			}
+			statuses = append(statuses, st)
+		}
+		return nil
+	})
+
+	return statuses, err
+}
+
+func (m *Migrator) withLock(ctx context.Context, fn func(*sql.Conn, map[int64]time.Time) error) (err error) {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return errors.Wrap(err, "can`t get connection")
+	}
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return errors.Wrap(err, "can`t take migration lock")
+	}
+	defer func() {
+		_, unlockErr := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID)
+		if err == nil && unlockErr != nil {
+			err = errors.Wrap(unlockErr, "can`t release migration lock")
+		}
+	}()
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations
+(
+    version    BIGINT PRIMARY KEY,
+    name       VARCHAR(255) NOT NULL,
+    applied_at TIMESTAMPTZ  NOT NULL DEFAULT now()
+)`)
+	if err != nil {
+		return errors.Wrap(err, "can`t create schema_migrations")
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return errors.Wrap(err, "can`t read schema_migrations")
+	}
+	defer rows.Close()
+
+	done := map[int64]time.Time{}
+	for rows.Next() {
+		var (
+			version int64
+			at      time.Time
+		)
+		if err = rows.Scan(&version, &at); err != nil {
+			return errors.Wrap(err, "can`t read schema_migrations")
+		}
+		done[version] = at
+	}
+	if err = rows.Err(); err != nil {
+		return errors.Wrap(err, "can`t read schema_migrations")
+	}
+
+	return fn(conn, done)
+}
+
+func inTx(ctx context.Context, conn *sql.Conn, script, record string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, script); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, record, args...); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s Status) String() string {
+	state := "pending"
+	if s.AppliedAt != nil {
+		state = "applied at " + s.AppliedAt.Format(time.RFC3339)
+	}
+
+	return fmt.Sprintf("%d_%s: %s", s.Version, s.Name, state)
+}
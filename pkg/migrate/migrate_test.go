@@ -0,0 +1,129 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"go.uber.org/zap"
+)
+
+type MigrateTestSuite struct {
+	suite.Suite
+	db   *sql.DB
+	mock sqlmock.Sqlmock
+	fs   fstest.MapFS
+}
+
+func TestMigrateSuite(t *testing.T) {
+	suite.RunSuite(t, new(MigrateTestSuite))
+}
+
+func (s *MigrateTestSuite) BeforeEach(t provider.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("error while creating sql mock")
+	}
+
+	s.db = db
+	s.mock = mock
+	s.fs = fstest.MapFS{
+		"0001_create_people.up.sql":    {Data: []byte("CREATE TABLE people ()")},
+		"0001_create_people.down.sql":  {Data: []byte("DROP TABLE people")},
+		"0002_add_version.up.sql":      {Data: []byte("ALTER TABLE people ADD version INT")},
+		"0002_add_version.down.sql":    {Data: []byte("ALTER TABLE people DROP version")},
+		"migrations.go":                {Data: []byte("package migrations")},
+		"0003_no_up_file_yet.down.sql": nil,
+	}
+}
+
+func (s *MigrateTestSuite) AfterEach(t provider.T) {
+	err := s.mock.ExpectationsWereMet()
+	t.Assert().NoError(err)
+	s.db.Close()
+}
+
+func (s *MigrateTestSuite) expectLock(applied ...int64) {
+	s.mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_lock($1)`)).
+		WithArgs(lockID).WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS schema_migrations`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	rows := sqlmock.NewRows([]string{"version", "applied_at"})
+	for _, v := range applied {
+		rows.AddRow(v, time.Now())
+	}
+	s.mock.ExpectQuery(regexp.QuoteMeta(`SELECT version, applied_at FROM schema_migrations`)).
+		WillReturnRows(rows)
+}
+
+func (s *MigrateTestSuite) expectUnlock() {
+	s.mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_unlock($1)`)).
+		WithArgs(lockID).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func (s *MigrateTestSuite) TestNewRequiresUpFile(t provider.T) {
+	_, err := New(s.db, zap.NewNop().Sugar(), s.fs)
+	t.Assert().Error(err)
+}
+
+func (s *MigrateTestSuite) TestUp(t provider.T) {
+	delete(s.fs, "0003_no_up_file_yet.down.sql")
+	m, err := New(s.db, zap.NewNop().Sugar(), s.fs)
+	t.Require().NoError(err)
+	t.Require().Len(m.Migrations, 2)
+
+	s.expectLock(1)
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta(`ALTER TABLE people ADD version INT`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`)).
+		WithArgs(int64(2), "add_version").WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectCommit()
+	s.expectUnlock()
+
+	applied, err := m.Up(context.Background())
+	t.Assert().NoError(err)
+	t.Assert().Len(applied, 1)
+	t.Assert().Equal(int64(2), applied[0].Version)
+}
+
+func (s *MigrateTestSuite) TestDown(t provider.T) {
+	delete(s.fs, "0003_no_up_file_yet.down.sql")
+	m, err := New(s.db, zap.NewNop().Sugar(), s.fs)
+	t.Require().NoError(err)
+
+	s.expectLock(1, 2)
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta(`ALTER TABLE people DROP version`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM schema_migrations WHERE version = $1`)).
+		WithArgs(int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectCommit()
+	s.expectUnlock()
+
+	reverted, err := m.Down(context.Background(), 1)
+	t.Assert().NoError(err)
+	t.Assert().Len(reverted, 1)
+}
+
+func (s *MigrateTestSuite) TestStatus(t provider.T) {
+	delete(s.fs, "0003_no_up_file_yet.down.sql")
+	m, err := New(s.db, zap.NewNop().Sugar(), s.fs)
+	t.Require().NoError(err)
+
+	s.expectLock(1)
+	s.expectUnlock()
+
+	statuses, err := m.Status(context.Background())
+	t.Assert().NoError(err)
+	t.Assert().Len(statuses, 2)
+	t.Assert().NotNil(statuses[0].AppliedAt)
+	t.Assert().Nil(statuses[1].AppliedAt)
+}
@@ -0,0 +1,105 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Davmie/person_service/models"
+	"github.com/Davmie/person_service/pkg/logger"
+	"github.com/Davmie/person_service/pkg/validator"
+	"github.com/pkg/errors"
+)
+
+// RequestIDHeader carries the id that correlates a response with the log
+// entries of its request. Error bodies repeat it.
+const RequestIDHeader = "X-Request-ID"
+
+type ErrorResponse struct {
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+type ValidationErrorResponse struct {
+	Message   string            `json:"message"`
+	Errors    map[string]string `json:"errors"`
+	RequestID string            `json:"request_id,omitempty"`
+}
+
+var errorStatuses = []struct {
+	err    error
+	status int
+}{
+	{models.ErrNotFound, http.StatusNotFound},
+	{models.ErrConflict, http.StatusConflict},
+	{models.ErrValidation, http.StatusBadRequest},
+	{models.ErrUnauthorized, http.StatusUnauthorized},
+	{models.ErrUnavailable, http.StatusServiceUnavailable},
+	{models.ErrPreconditionFailed, http.StatusPreconditionFailed},
+	{models.ErrPreconditionRequired, http.StatusPreconditionRequired},
+	{models.ErrPatchTestFailed, http.StatusConflict},
+	{models.ErrBatchAborted, http.StatusFailedDependency},
+}
+
+func JSON(w http.ResponseWriter, logger logger.Logger, status int, v interface{}) {
+	resp, err := json.Marshal(v)
+	if err != nil {
+		logger.Errorw("can`t marshal response",
+			"err:", err.Error())
+		Message(w, logger, http.StatusInternalServerError, "can`t make response")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_, err = w.Write(resp)
+	if err != nil {
+		logger.Errorw("can`t write response",
+			"err:", err.Error())
+	}
+}
+
+func Message(w http.ResponseWriter, logger logger.Logger, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	resp, _ := json.Marshal(ErrorResponse{Message: msg, RequestID: w.Header().Get(RequestIDHeader)})
+	_, err := w.Write(resp)
+	if err != nil {
+		logger.Errorw("can`t write response",
+			"err:", err.Error())
+	}
+}
+
+func Validation(w http.ResponseWriter, logger logger.Logger, errs validator.Errors) {
+	JSON(w, logger, http.StatusBadRequest, ValidationErrorResponse{
+		Message:   models.ErrValidation.Error(),
+		Errors:    errs,
+		RequestID: w.Header().Get(RequestIDHeader),
+	})
+}
+
+// Error picks the response status by the domain error found in err's chain.
+// Unknown errors are reported as 500 without leaking their text.
+func Error(w http.ResponseWriter, logger logger.Logger, err error) {
+	var errs validator.Errors
+	if errors.As(err, &errs) {
+		Validation(w, logger, errs)
+		return
+	}
+
+	status, msg := Status(err)
+	Message(w, logger, status, msg)
+}
+
+// Status returns the status and message Error responds with for a domain
+// error.
+func Status(err error) (int, string) {
+	for _, es := range errorStatuses {
+		if errors.Is(err, es.err) {
+			return es.status, es.err.Error()
+		}
+	}
+
+	return http.StatusInternalServerError, "internal server error"
+}
@@ -0,0 +1,267 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Davmie/person_service/pkg/logger"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pkg/errors"
+)
+
+const defaultKeyID = "default"
+
+// KeySpec describes a signing key stored in a file. HS256 files hold the raw
+// secret, RS256 and EdDSA files hold a PEM encoded private key. The key is
+// used for signing from NotBefore on.
+type KeySpec struct {
+	ID        string
+	Algorithm string
+	File      string
+	NotBefore time.Time
+}
+
+type Key struct {
+	ID        string
+	Method    jwt.SigningMethod
+	NotBefore time.Time
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// KeyRing holds the signing keys ordered by NotBefore. The newest active key
+// signs new tokens; an older key still verifies tokens until Grace has passed
+// since its successor became active.
+type KeyRing struct {
+	Grace time.Duration
+
+	mu    sync.RWMutex
+	specs []KeySpec
+	keys  []*Key
+}
+
+func NewHMACKeyRing(secret []byte) *KeyRing {
+	return &KeyRing{
+		keys: []*Key{{
+			ID:        defaultKeyID,
+			Method:    jwt.SigningMethodHS256,
+			signKey:   secret,
+			verifyKey: secret,
+		}},
+	}
+}
+
+func LoadKeyRing(specs []KeySpec, grace time.Duration) (*KeyRing, error) {
+	kr := &KeyRing{Grace: grace, specs: specs}
+	if err := kr.Reload(); err != nil {
+		return nil, err
+	}
+
+	return kr, nil
+}
+
+// Reload reads the key files again, picking up keys that were replaced on
+// disk.
+func (kr *KeyRing) Reload() error {
+	keys := make([]*Key, 0, len(kr.specs))
+	ids := map[string]bool{}
+	for _, spec := range kr.specs {
+		if ids[spec.ID] {
+			return errors.Errorf("duplicate key id %q", spec.ID)
+		}
+		ids[spec.ID] = true
+
+		key, err := loadKey(spec)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return errors.New("no signing keys")
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].NotBefore.Before(keys[j].NotBefore)
+	})
+
+	kr.mu.Lock()
+	kr.keys = keys
+	kr.mu.Unlock()
+
+	return nil
+}
+
+// Watch reloads the key files every interval until ctx is done.
+func (kr *KeyRing) Watch(ctx context.Context, interval time.Duration, logger logger.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := kr.Reload(); err != nil {
+				logger.Errorw("can`t reload signing keys",
+					"err:", err.Error())
+			}
+		}
+	}
+}
+
+// Signing returns the newest key whose NotBefore has passed.
+func (kr *KeyRing) Signing() (*Key, error) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	now := time.Now()
+	for i := len(kr.keys) - 1; i >= 0; i-- {
+		if !kr.keys[i].NotBefore.After(now) {
+			return kr.keys[i], nil
+		}
+	}
+
+	return nil, errors.New("no active signing key")
+}
+
+// Verifying returns the key with the given id if it may still verify tokens.
+func (kr *KeyRing) Verifying(kid string) (*Key, error) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	now := time.Now()
+	for i, key := range kr.keys {
+		if key.ID != kid {
+			continue
+		}
+		if key.NotBefore.After(now) {
+			return nil, errors.Errorf("key %q is not active yet", kid)
+		}
+		if kr.retired(i, now) {
+			return nil, errors.Errorf("key %q is retired", kid)
+		}
+		return key, nil
+	}
+
+	return nil, errors.Errorf("unknown key %q", kid)
+}
+
+// retired reports whether the grace period of the i-th key, started when the
+// next key became active, is over. The caller must hold kr.mu.
+func (kr *KeyRing) retired(i int, now time.Time) bool {
+	if i+1 >= len(kr.keys) {
+		return false
+	}
+
+	return now.Sub(kr.keys[i+1].NotBefore) > kr.Grace
+}
+
+// Algorithms lists the signing algorithms of all keys in the ring.
+func (kr *KeyRing) Algorithms() []string {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	seen := map[string]bool{}
+	var algs []string
+	for _, key := range kr.keys {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			algs = append(algs, alg)
+		}
+	}
+
+	return algs
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public parts of the asymmetric keys that still verify
+// tokens, including keys that are not active yet. HMAC keys are never
+// published.
+func (kr *KeyRing) JWKS() JWKSet {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	now := time.Now()
+	set := JWKSet{Keys: []JWK{}}
+	for i, key := range kr.keys {
+		if kr.retired(i, now) {
+			continue
+		}
+
+		jwk := JWK{Kid: key.ID, Alg: key.Method.Alg(), Use: "sig"}
+		switch pub := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
+
+func loadKey(spec KeySpec) (*Key, error) {
+	data, err := os.ReadFile(spec.File)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can`t read key %q", spec.ID)
+	}
+
+	key := &Key{ID: spec.ID, NotBefore: spec.NotBefore}
+
+	switch spec.Algorithm {
+	case jwt.SigningMethodHS256.Alg():
+		secret := bytes.TrimSpace(data)
+		if len(secret) == 0 {
+			return nil, errors.Errorf("key %q is empty", spec.ID)
+		}
+		key.Method, key.signKey, key.verifyKey = jwt.SigningMethodHS256, secret, secret
+	case jwt.SigningMethodRS256.Alg():
+		private, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "can`t parse key %q", spec.ID)
+		}
+		key.Method, key.signKey, key.verifyKey = jwt.SigningMethodRS256, private, &private.PublicKey
+	case jwt.SigningMethodEdDSA.Alg():
+		private, err := jwt.ParseEdPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "can`t parse key %q", spec.ID)
+		}
+		key.Method, key.signKey = jwt.SigningMethodEdDSA, private
+		key.verifyKey = private.(crypto.Signer).Public()
+	default:
+		return nil, errors.Errorf("key %q has unsupported algorithm %q", spec.ID, spec.Algorithm)
+	}
+
+	return key, nil
+}
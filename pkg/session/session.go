@@ -1,13 +1,21 @@
 package session
 
 import (
+	"context"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"time"
 
 	"github.com/pkg/errors"
 )
 
-var tokenKey = []byte("fvoNImvpdms023sv0s9vs")
+const (
+	AccessToken  = "access"
+	RefreshToken = "refresh"
+
+	defaultAccessTTL  = 15 * time.Minute
+	defaultRefreshTTL = 7 * 24 * time.Hour
+)
 
 type UserClaims struct {
 	ID   int    `json:"id"`
@@ -16,43 +24,126 @@ type UserClaims struct {
 
 type Claims struct {
 	User UserClaims `json:"user"`
+	Type string     `json:"typ"`
 	jwt.RegisteredClaims
 }
 
-type JWTSessionsManager struct{}
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+type JWTSessionsManager struct {
+	Keys        *KeyRing
+	AccessTTL   time.Duration
+	RefreshTTL  time.Duration
+	Revocations RevocationChecker
+}
+
+func NewJWTSessionsManager(key string) JWTSessionsManager {
+	return JWTSessionsManager{
+		Keys:       NewHMACKeyRing([]byte(key)),
+		AccessTTL:  defaultAccessTTL,
+		RefreshTTL: defaultRefreshTTL,
+	}
+}
 
-func (jsm JWTSessionsManager) GetUser(inToken string) (int, string, error) {
-	token, err := jwt.ParseWithClaims(inToken, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return tokenKey, nil
-	})
+func (jsm JWTSessionsManager) GetUser(ctx context.Context, inToken string) (int, string, error) {
+	claims, err := jsm.ParseToken(ctx, inToken, AccessToken)
+	if err != nil {
+		return -1, "", err
+	}
+
+	return claims.User.ID, claims.User.Role, nil
+}
+
+// ParseToken validates inToken, checks that it has the expected type and
+// that its jti has not been revoked. Errors name the token by its kid or jti
+// only, since they end up in logs.
+func (jsm JWTSessionsManager) ParseToken(ctx context.Context, inToken, tokenType string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(inToken, &Claims{}, jsm.keyFunc, jwt.WithValidMethods(jsm.Keys.Algorithms()))
+
+	if err != nil {
+		return nil, errors.Wrap(err, "can`t parse or validate session token")
+	}
 
 	claims, ok := token.Claims.(*Claims)
 
 	if !ok || !token.Valid {
-		return -1, "", errors.Wrapf(err, "can`t parse or validate session token \"%s\"", inToken)
+		return nil, errors.Errorf("can`t parse or validate session token with kid %v", token.Header["kid"])
 	}
 
-	return claims.User.ID, claims.User.Role, nil
+	if claims.Type != tokenType {
+		return nil, errors.Errorf("expected %s token, got \"%s\" with jti %s", tokenType, claims.Type, claims.ID)
+	}
+
+	if jsm.Revocations != nil {
+		revoked, err := jsm.Revocations.IsRevoked(ctx, claims.ID)
+		if err != nil {
+			return nil, errors.Wrap(err, "can`t check token revocation")
+		}
+		if revoked {
+			return nil, errors.Errorf("token %s is revoked", claims.ID)
+		}
+	}
+
+	return claims, nil
 }
 
 func (jsm JWTSessionsManager) CreateSession(id int, role string) (string, error) {
+	return jsm.createToken(id, role, AccessToken, jsm.AccessTTL)
+}
+
+func (jsm JWTSessionsManager) CreateRefreshToken(id int, role string) (string, error) {
+	return jsm.createToken(id, role, RefreshToken, jsm.RefreshTTL)
+}
+
+func (jsm JWTSessionsManager) createToken(id int, role, tokenType string, ttl time.Duration) (string, error) {
+	now := time.Now()
 	claims := Claims{
 		UserClaims{
 			ID:   id,
 			Role: role,
 		},
+		tokenType,
 		jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().AddDate(0, 0, 7)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	key, err := jsm.Keys.Signing()
+	if err != nil {
+		return "", errors.Wrap(err, "can`t choose signing key")
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
 
-	tokenString, err := token.SignedString(tokenKey)
+	tokenString, err := token.SignedString(key.signKey)
 	if err != nil {
 		return "", errors.Wrap(err, "failed to convert token to string")
 	}
 
 	return tokenString, nil
 }
+
+// keyFunc picks the verification key by the kid header and rejects tokens
+// whose algorithm differs from the one the key was created for.
+func (jsm JWTSessionsManager) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, ok := token.Header["kid"].(string)
+	if !ok {
+		return nil, errors.New("token has no kid header")
+	}
+
+	key, err := jsm.Keys.Verifying(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+	}
+
+	return key.verifyKey, nil
+}
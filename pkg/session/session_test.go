@@ -0,0 +1,151 @@
+package session
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+)
+
+type SessionTestSuite struct {
+	suite.Suite
+}
+
+func TestSessionSuite(t *testing.T) {
+	suite.RunSuite(t, new(SessionTestSuite))
+}
+
+func writeKeys(t provider.T) []KeySpec {
+	dir := t.TempDir()
+	now := time.Now()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	t.Require().NoError(err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	t.Require().NoError(err)
+
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		t.Require().NoError(os.WriteFile(path, data, 0o600))
+		return path
+	}
+	pemKey := func(key interface{}) []byte {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		t.Require().NoError(err)
+		return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	}
+
+	return []KeySpec{
+		{ID: "hs", Algorithm: "HS256", File: write("hs.key", []byte("hmac-secret\n")), NotBefore: now.Add(-2 * time.Hour)},
+		{ID: "rs", Algorithm: "RS256", File: write("rs.pem", pemKey(rsaKey)), NotBefore: now.Add(-time.Hour)},
+		{ID: "ed", Algorithm: "EdDSA", File: write("ed.pem", pemKey(edKey)), NotBefore: now.Add(time.Hour)},
+	}
+}
+
+func (s *SessionTestSuite) TestRotation(t provider.T) {
+	specs := writeKeys(t)
+	ctx := context.Background()
+
+	ring, err := LoadKeyRing(specs, 2*time.Hour)
+	t.Require().NoError(err)
+	jsm := NewJWTSessionsManager("")
+	jsm.Keys = ring
+
+	key, err := ring.Signing()
+	t.Require().NoError(err)
+	t.Assert().Equal("rs", key.ID)
+
+	token, err := jsm.CreateSession(1, "viewer")
+	t.Require().NoError(err)
+	userID, _, err := jsm.GetUser(ctx, token)
+	t.Assert().NoError(err)
+	t.Assert().Equal(1, userID)
+
+	oldRing, err := LoadKeyRing(specs[:1], 0)
+	t.Require().NoError(err)
+	old := NewJWTSessionsManager("")
+	old.Keys = oldRing
+	oldToken, err := old.CreateSession(2, "viewer")
+	t.Require().NoError(err)
+
+	_, _, err = jsm.GetUser(ctx, oldToken)
+	t.Assert().NoError(err, "old key is within the grace period")
+
+	ring.Grace = 30 * time.Minute
+	_, _, err = jsm.GetUser(ctx, oldToken)
+	t.Assert().Error(err, "old key is retired")
+}
+
+func (s *SessionTestSuite) TestRejectsOtherAlgorithms(t provider.T) {
+	specs := writeKeys(t)
+	ring, err := LoadKeyRing(specs, time.Hour)
+	t.Require().NoError(err)
+	jsm := NewJWTSessionsManager("")
+	jsm.Keys = ring
+
+	rsKey, err := ring.Verifying("rs")
+	t.Require().NoError(err)
+	publicDER, err := x509.MarshalPKIXPublicKey(rsKey.verifyKey)
+	t.Require().NoError(err)
+
+	claims := Claims{User: UserClaims{ID: 1, Role: "admin"}, Type: AccessToken}
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	forged.Header["kid"] = "rs"
+	forgedString, err := forged.SignedString(publicDER)
+	t.Require().NoError(err)
+
+	unsigned := jwt.NewWithClaims(jwt.SigningMethodNone, claims)
+	unsigned.Header["kid"] = "hs"
+	unsignedString, err := unsigned.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	t.Require().NoError(err)
+
+	for name, token := range map[string]string{"HS256 with RSA key": forgedString, "none": unsignedString} {
+		t.Run(name, func(t provider.T) {
+			_, _, err := jsm.GetUser(context.Background(), token)
+			t.Assert().Error(err)
+		})
+	}
+}
+
+func (s *SessionTestSuite) TestErrorsOmitToken(t provider.T) {
+	jsm := NewJWTSessionsManager("secret")
+	ctx := context.Background()
+
+	refresh, err := jsm.CreateRefreshToken(1, "viewer")
+	t.Require().NoError(err)
+	jsm.AccessTTL = -time.Minute
+	expired, err := jsm.CreateSession(1, "viewer")
+	t.Require().NoError(err)
+
+	for name, token := range map[string]string{"wrong type": refresh, "expired": expired, "garbage": "not.a.token"} {
+		t.Run(name, func(t provider.T) {
+			_, _, err := jsm.GetUser(ctx, token)
+			t.Require().Error(err)
+			t.Assert().NotContains(err.Error(), token)
+		})
+	}
+}
+
+func (s *SessionTestSuite) TestJWKS(t provider.T) {
+	ring, err := LoadKeyRing(writeKeys(t), time.Hour)
+	t.Require().NoError(err)
+
+	set := ring.JWKS()
+	t.Require().Len(set.Keys, 2)
+	t.Assert().Equal("rs", set.Keys[0].Kid)
+	t.Assert().Equal("RSA", set.Keys[0].Kty)
+	t.Assert().Equal("AQAB", set.Keys[0].E)
+	t.Assert().Equal("ed", set.Keys[1].Kid)
+	t.Assert().Equal("Ed25519", set.Keys[1].Crv)
+}
@@ -0,0 +1,135 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	"github.com/Davmie/person_service/pkg/config"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ServiceName = "person_service"
+
+	instrumentation = "github.com/Davmie/person_service"
+)
+
+// ExporterFactory creates the span exporter selected by tracing.exporter.
+type ExporterFactory func(ctx context.Context, cfg config.TracingConfig) (sdktrace.SpanExporter, error)
+
+var (
+	exportersMu sync.RWMutex
+	exporters   = map[string]ExporterFactory{
+		"stdout": func(ctx context.Context, cfg config.TracingConfig) (sdktrace.SpanExporter, error) {
+			return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		},
+		"file": func(ctx context.Context, cfg config.TracingConfig) (sdktrace.SpanExporter, error) {
+			f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+			if err != nil {
+				return nil, errors.Wrap(err, "can`t open span file")
+			}
+			exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+			if err != nil {
+				f.Close()
+				return nil, err
+			}
+			return &fileExporter{SpanExporter: exporter, file: f}, nil
+		},
+		"otlp": func(ctx context.Context, cfg config.TracingConfig) (sdktrace.SpanExporter, error) {
+			return otlptracehttp.New(ctx,
+				otlptracehttp.WithEndpoint(cfg.OTLPEndpoint),
+				otlptracehttp.WithInsecure(),
+			)
+		},
+	}
+)
+
+// fileExporter closes the span file once the exporter is shut down.
+type fileExporter struct {
+	sdktrace.SpanExporter
+	file *os.File
+}
+
+func (fe *fileExporter) Shutdown(ctx context.Context) error {
+	err := fe.SpanExporter.Shutdown(ctx)
+	if closeErr := fe.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// RegisterExporter makes an exporter available to tracing.exporter under
+// name, replacing a built-in one with the same name.
+func RegisterExporter(name string, f ExporterFactory) {
+	exportersMu.Lock()
+	defer exportersMu.Unlock()
+
+	exporters[name] = f
+}
+
+// Setup installs the global tracer provider and the W3C trace context
+// propagator. The returned func flushes and stops the exporter. With the
+// "none" exporter incoming trace context is still propagated, but no spans
+// are recorded.
+func Setup(ctx context.Context, cfg config.TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if cfg.Exporter == "" || cfg.Exporter == "none" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exportersMu.RLock()
+	factory, ok := exporters[cfg.Exporter]
+	exportersMu.RUnlock()
+	if !ok {
+		return nil, errors.Errorf("unknown tracing exporter %q", cfg.Exporter)
+	}
+
+	exporter, err := factory(ctx, cfg)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can`t create %s exporter", cfg.Exporter)
+	}
+
+	tp := NewProvider(exporter, cfg.SampleRatio)
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown, nil
+}
+
+// NewProvider batches spans to exporter, sampling new traces at ratio and
+// following the sampling decision of incoming ones.
+func NewProvider(exporter sdktrace.SpanExporter, ratio float64) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(ServiceName))),
+	)
+}
+
+// Tracer returns the tracer of the service from the global provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
+
+// End records err on span, if any, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,100 @@
+package tracing
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Davmie/person_service/pkg/config"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type TracingTestSuite struct {
+	suite.Suite
+	exporter *tracetest.InMemoryExporter
+	provider *sdktrace.TracerProvider
+}
+
+func TestTracingSuite(t *testing.T) {
+	suite.RunSuite(t, new(TracingTestSuite))
+}
+
+func (s *TracingTestSuite) BeforeEach(t provider.T) {
+	s.exporter = tracetest.NewInMemoryExporter()
+	s.provider = NewProvider(s.exporter, 1)
+	otel.SetTracerProvider(s.provider)
+}
+
+func (s *TracingTestSuite) spans(t provider.T) tracetest.SpanStubs {
+	t.Require().NoError(s.provider.ForceFlush(context.Background()))
+	return s.exporter.GetSpans()
+}
+
+func (s *TracingTestSuite) TestGormPlugin(t provider.T) {
+	sqlDB, mock, err := sqlmock.New()
+	t.Require().NoError(err)
+	defer sqlDB.Close()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB, PreferSimpleProtocol: true}), &gorm.Config{})
+	t.Require().NoError(err)
+	t.Require().NoError(db.Use(GormPlugin{}))
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "people" WHERE id = $1`)).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	ctx, parent := Tracer().Start(context.Background(), "parent")
+	var rows []struct{ ID int }
+	err = db.WithContext(ctx).Table("people").Where("id = ?", 7).Find(&rows).Error
+	t.Require().NoError(err)
+	parent.End()
+
+	spans := s.spans(t)
+	t.Require().Len(spans, 2)
+
+	query := spans[0]
+	t.Assert().Equal("gorm.query", query.Name)
+	t.Assert().Equal(parent.SpanContext().SpanID(), query.Parent.SpanID())
+
+	attrs := map[string]string{}
+	for _, kv := range query.Attributes {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	t.Assert().Equal(`SELECT * FROM "people" WHERE id = $1`, attrs["db.statement"])
+	t.Assert().Equal("people", attrs["db.collection.name"])
+	t.Assert().NotContains(attrs["db.statement"], "7")
+}
+
+func (s *TracingTestSuite) TestEnd(t provider.T) {
+	_, span := Tracer().Start(context.Background(), "failing")
+	End(span, context.Canceled)
+
+	spans := s.spans(t)
+	t.Require().Len(spans, 1)
+	t.Assert().Equal(codes.Error, spans[0].Status.Code)
+	t.Assert().Len(spans[0].Events, 1)
+}
+
+func (s *TracingTestSuite) TestSetup(t provider.T) {
+	var got config.TracingConfig
+	RegisterExporter("test", func(ctx context.Context, cfg config.TracingConfig) (sdktrace.SpanExporter, error) {
+		got = cfg
+		return tracetest.NewInMemoryExporter(), nil
+	})
+
+	shutdown, err := Setup(context.Background(), config.TracingConfig{Exporter: "test", SampleRatio: 1})
+	t.Require().NoError(err)
+	t.Assert().Equal("test", got.Exporter)
+	t.Assert().NoError(shutdown(context.Background()))
+
+	_, err = Setup(context.Background(), config.TracingConfig{Exporter: "jaeger"})
+	t.Assert().Error(err)
+}
@@ -0,0 +1,67 @@
+package validator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Errors maps a field name to the first violation found for it.
+type Errors map[string]string
+
+func (e Errors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field := range e {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	msgs := make([]string, 0, len(fields))
+	for _, field := range fields {
+		msgs = append(msgs, field+": "+e[field])
+	}
+
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+type Validator struct {
+	errs Errors
+}
+
+func New() *Validator {
+	return &Validator{errs: Errors{}}
+}
+
+func (v *Validator) Add(field, msg string) {
+	if _, ok := v.errs[field]; !ok {
+		v.errs[field] = msg
+	}
+}
+
+func (v *Validator) Required(field, value string) {
+	if value == "" {
+		v.Add(field, "is required")
+	}
+}
+
+func (v *Validator) MaxLen(field, value string, max int) {
+	if utf8.RuneCountInString(value) > max {
+		v.Add(field, fmt.Sprintf("must be at most %d characters", max))
+	}
+}
+
+func (v *Validator) Range(field string, value, min, max int) {
+	if value < min || value > max {
+		v.Add(field, fmt.Sprintf("must be between %d and %d", min, max))
+	}
+}
+
+// Errors returns the collected violations or nil if there are none.
+func (v *Validator) Errors() Errors {
+	if len(v.errs) == 0 {
+		return nil
+	}
+
+	return v.errs
+}